@@ -0,0 +1,121 @@
+package sensonet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Classes of unsuccessful responses of the API. An APIError matches one of them with errors.Is().
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrConflict     = errors.New("conflict")
+	ErrServerError  = errors.New("server error")
+)
+
+// maximum size of an error body that is read
+const maxErrorBodySize = 64 * 1024
+
+// APIError is returned for responses of the API with a status code other than HTTP 2xx.
+// ErrorCode, Message and TraceID are decoded from the error json of the API, if the body contains one.
+// It wraps a StatusError, so errors.As() works for both types.
+type APIError struct {
+	StatusCode int
+	ErrorCode  string
+	Message    string
+	TraceID    string
+	RetryAfter time.Duration // only set for rate-limited requests that announce when to retry
+	Body       []byte
+	status     StatusError
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.status.Error())
+	if e.ErrorCode != "" {
+		fmt.Fprintf(&b, ": %s", e.ErrorCode)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.TraceID != "" {
+		fmt.Fprintf(&b, " (trace id %s)", e.TraceID)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.status
+}
+
+// Is classifies the error by its status code (ErrUnauthorized, ErrForbidden, ErrNotFound, ErrRateLimited, ErrConflict or ErrServerError)
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// newAPIError creates the APIError for resp. body is the already read body of resp.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		status:     StatusError{resp: resp},
+	}
+
+	// the API and its gateway do not use the same names in all error responses
+	var res struct {
+		ErrorCode  string `json:"errorCode"`
+		Code       any    `json:"code"`
+		Message    string `json:"message"`
+		ErrMessage string `json:"errorMessage"`
+		TraceID    string `json:"traceId"`
+	}
+	if json.Unmarshal(body, &res) == nil {
+		e.ErrorCode = res.ErrorCode
+		if e.ErrorCode == "" && res.Code != nil {
+			e.ErrorCode = fmt.Sprint(res.Code)
+		}
+		e.Message = res.Message
+		if e.Message == "" {
+			e.Message = res.ErrMessage
+		}
+		e.TraceID = res.TraceID
+	}
+	if e.TraceID == "" {
+		e.TraceID = resp.Header.Get("traceparent")
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return e
+}
+
+// readAPIError reads and closes the body of resp and returns the APIError for it
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return newAPIError(resp, body)
+}
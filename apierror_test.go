@@ -0,0 +1,176 @@
+package sensonet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		errorCode  string
+		message    string
+		traceID    string
+		retryAfter time.Duration
+		is         error
+	}{
+		{
+			name:      "api error",
+			status:    http.StatusBadRequest,
+			body:      `{"errorCode":"INVALID_SETPOINT","message":"setpoint out of range","traceId":"abc"}`,
+			errorCode: "INVALID_SETPOINT",
+			message:   "setpoint out of range",
+			traceID:   "abc",
+		},
+		{
+			name:      "gateway error with numeric code",
+			status:    http.StatusUnauthorized,
+			body:      `{"code":401,"errorMessage":"token expired"}`,
+			errorCode: "401",
+			message:   "token expired",
+			is:        ErrUnauthorized,
+		},
+		{
+			name:      "trace id from header",
+			status:    http.StatusNotFound,
+			header:    http.Header{"Traceparent": {"00-trace-01"}},
+			body:      `{"errorCode":"NOT_FOUND"}`,
+			errorCode: "NOT_FOUND",
+			traceID:   "00-trace-01",
+			is:        ErrNotFound,
+		},
+		{
+			name:   "no json body",
+			status: http.StatusBadGateway,
+			body:   `<html>bad gateway</html>`,
+			is:     ErrServerError,
+		},
+		{
+			name:       "rate limited with retry-after",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"30"}},
+			retryAfter: 30 * time.Second,
+			is:         ErrRateLimited,
+		},
+		{
+			name:   "retry-after ignored for other status codes",
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Retry-After": {"30"}},
+			is:     ErrServerError,
+		},
+		{
+			name:   "conflict",
+			status: http.StatusConflict,
+			is:     ErrConflict,
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			is:     ErrForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+			resp := &http.Response{
+				StatusCode: tc.status,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tc.body)),
+			}
+
+			err := ResponseError(resp)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %T, want *APIError", err)
+			}
+			if apiErr.StatusCode != tc.status {
+				t.Errorf("status code: got %d, want %d", apiErr.StatusCode, tc.status)
+			}
+			if apiErr.ErrorCode != tc.errorCode {
+				t.Errorf("error code: got %q, want %q", apiErr.ErrorCode, tc.errorCode)
+			}
+			if apiErr.Message != tc.message {
+				t.Errorf("message: got %q, want %q", apiErr.Message, tc.message)
+			}
+			if apiErr.TraceID != tc.traceID {
+				t.Errorf("trace id: got %q, want %q", apiErr.TraceID, tc.traceID)
+			}
+			if apiErr.RetryAfter != tc.retryAfter {
+				t.Errorf("retry after: got %v, want %v", apiErr.RetryAfter, tc.retryAfter)
+			}
+			if string(apiErr.Body) != tc.body {
+				t.Errorf("body: got %q, want %q", apiErr.Body, tc.body)
+			}
+
+			for _, target := range []error{ErrUnauthorized, ErrForbidden, ErrNotFound, ErrRateLimited, ErrConflict, ErrServerError} {
+				if got, want := errors.Is(err, target), target == tc.is; got != want {
+					t.Errorf("errors.Is(%v): got %v, want %v", target, got, want)
+				}
+			}
+
+			var statusErr StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatal("APIError does not wrap a StatusError")
+			}
+			if statusErr.StatusCode() != tc.status {
+				t.Errorf("StatusError status code: got %d, want %d", statusErr.StatusCode(), tc.status)
+			}
+		})
+	}
+}
+
+func TestConnectionAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/homes":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"errorCode":"ACCESS_DENIED","message":"no access","traceId":"t1"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := NewConnection(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conn.GetHomesCtx(context.Background())
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v, want ErrForbidden", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %T, want *APIError", err)
+	}
+	if apiErr.ErrorCode != "ACCESS_DENIED" || apiErr.Message != "no access" || apiErr.TraceID != "t1" {
+		t.Errorf("unexpected api error: %+v", apiErr)
+	}
+
+	// systems without meta info are TLI systems
+	controlIdentifier, err := conn.GetControlIdentifierCtx(context.Background(), "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if controlIdentifier != CONTROL_IDENTIFIER_TLI {
+		t.Errorf("control identifier: got %q, want %q", controlIdentifier, CONTROL_IDENTIFIER_TLI)
+	}
+}
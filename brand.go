@@ -0,0 +1,100 @@
+package sensonet
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	BRAND_VAILLANT      = "vaillant"
+	BRAND_SAUNIER_DUVAL = "sdbg"
+	BRAND_BULEX         = "bulex"
+)
+
+// Endpoints holds the base urls of the identity provider and of the API that are used by a connection
+type Endpoints struct {
+	ApiURLBase  string
+	AuthURLBase string
+}
+
+// Currently all brands of the Vaillant group share the same backend
+var brandEndpoints = map[string]Endpoints{
+	BRAND_VAILLANT:      {ApiURLBase: API_URL_BASE, AuthURLBase: AUTH_BASE_URL},
+	BRAND_SAUNIER_DUVAL: {ApiURLBase: API_URL_BASE, AuthURLBase: AUTH_BASE_URL},
+	BRAND_BULEX:         {ApiURLBase: API_URL_BASE, AuthURLBase: AUTH_BASE_URL},
+}
+
+// Returns the endpoints for the given brand. For an unknown brand, the endpoints of myVaillant are returned.
+func EndpointsForBrand(brand string) Endpoints {
+	if endpoints, ok := brandEndpoints[brand]; ok {
+		return endpoints
+	}
+	return brandEndpoints[BRAND_VAILLANT]
+}
+
+// Profile bundles the brand and country specific settings that are used for the login
+// and for the http requests to the API
+type Profile struct {
+	Brand           string
+	Realm           string
+	ClientID        string
+	RedirectURL     string
+	AppIdentifier   string
+	Locale          string
+	SubscriptionKey string
+	Endpoints       Endpoints
+}
+
+// Returns the profile for brand and country (e.g. "germany", "austria", "belgium" or "unitedkingdom").
+// If country is empty, "germany" is used. The fields of the returned profile can be adjusted before it is used.
+func ProfileForBrand(brand, country string) Profile {
+	if brand == "" {
+		brand = BRAND_VAILLANT
+	}
+	if country == "" {
+		country = "germany"
+	}
+	return Profile{
+		Brand:           brand,
+		Realm:           fmt.Sprintf("%s-%s-b2c", brand, strings.ToLower(country)),
+		ClientID:        CLIENT_ID,
+		RedirectURL:     REDIRECT_URL,
+		AppIdentifier:   strings.ToUpper(brand),
+		Locale:          LOCALE_DEFAULT,
+		SubscriptionKey: SUBSCRIPTION_KEY,
+		Endpoints:       EndpointsForBrand(brand),
+	}
+}
+
+// withDefaults fills the empty fields of a partially filled profile from the profile of its brand
+func (p Profile) withDefaults() Profile {
+	def := ProfileForBrand(p.Brand, "")
+	if p.Brand == "" {
+		p.Brand = def.Brand
+	}
+	if p.Realm == "" {
+		p.Realm = def.Realm
+	}
+	if p.ClientID == "" {
+		p.ClientID = def.ClientID
+	}
+	if p.RedirectURL == "" {
+		p.RedirectURL = def.RedirectURL
+	}
+	if p.AppIdentifier == "" {
+		p.AppIdentifier = def.AppIdentifier
+	}
+	if p.Locale == "" {
+		p.Locale = def.Locale
+	}
+	if p.SubscriptionKey == "" {
+		p.SubscriptionKey = def.SubscriptionKey
+	}
+	if p.Endpoints.ApiURLBase == "" {
+		p.Endpoints.ApiURLBase = def.Endpoints.ApiURLBase
+	}
+	if p.Endpoints.AuthURLBase == "" {
+		p.Endpoints.AuthURLBase = def.Endpoints.AuthURLBase
+	}
+	return p
+}
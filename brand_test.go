@@ -0,0 +1,74 @@
+package sensonet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestPartialProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		want    http.Header
+	}{
+		{
+			name:    "brand only",
+			profile: Profile{Brand: BRAND_BULEX},
+			want: http.Header{
+				"X-App-Identifier":          {"BULEX"},
+				"X-Client-Locale":           {LOCALE_DEFAULT},
+				"Ocp-Apim-Subscription-Key": {SUBSCRIPTION_KEY},
+			},
+		},
+		{
+			name:    "locale only",
+			profile: Profile{Locale: "de-DE"},
+			want: http.Header{
+				"X-App-Identifier":          {"VAILLANT"},
+				"X-Client-Locale":           {"de-DE"},
+				"Ocp-Apim-Subscription-Key": {SUBSCRIPTION_KEY},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var header http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				_, _ = w.Write([]byte("[]"))
+			}))
+			defer srv.Close()
+
+			// WithBaseURL takes precedence over the endpoints of the profile, regardless of the order
+			conn, err := NewConnection(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), WithBaseURL(srv.URL), WithProfile(tc.profile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := conn.GetHomesCtx(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			for k, v := range tc.want {
+				if got := header.Values(k); strings.Join(got, ",") != strings.Join(v, ",") {
+					t.Errorf("header %s: got %v, want %v", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestOauth2ConfigForPartialProfile(t *testing.T) {
+	oc := Oauth2ConfigForProfile(Profile{Brand: BRAND_SAUNIER_DUVAL})
+	if oc.ClientID != CLIENT_ID || oc.RedirectURL != REDIRECT_URL {
+		t.Errorf("got client id %q and redirect url %q, want the defaults", oc.ClientID, oc.RedirectURL)
+	}
+	if want := AUTH_BASE_URL + "/sdbg-germany-b2c/"; !strings.HasPrefix(oc.Endpoint.AuthURL, want) {
+		t.Errorf("auth url: got %q, want prefix %q", oc.Endpoint.AuthURL, want)
+	}
+}
@@ -3,7 +3,9 @@ package sensonet
 // Copied from https://github.com/evcc-io/evcc
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"sync"
 	"time"
@@ -26,8 +28,164 @@ const (
 	backoffDuration = 5 * time.Second
 )
 
+// ResetCached resets all caches of the process, those without CacheGroup and those of all open CacheGroups.
+//
+// Deprecated: ResetCached is only kept for compatibility. Use CacheGroup.Reset() or Controller.ResetCache()
+// to reset the caches of a single controller.
 func ResetCached() {
 	bus.Publish(reset)
+
+	groups.mux.Lock()
+	open := make([]*CacheGroup, 0, len(groups.m))
+	for g := range groups.m {
+		open = append(open, g)
+	}
+	groups.mux.Unlock()
+
+	for _, g := range open {
+		g.Reset()
+	}
+}
+
+// open cache groups, needed for ResetCached()
+var groups = struct {
+	mux sync.Mutex
+	m   map[*CacheGroup]struct{}
+}{m: make(map[*CacheGroup]struct{})}
+
+// CacheGroup is a group of caches that are reset together, e.g. all caches of a Controller.
+// Caches of a group are not reset by other groups. Close() releases the group and its caches.
+// Background updates of the caches are bound to the group and are cancelled by Close().
+type CacheGroup struct {
+	mux    sync.Mutex
+	caches []interface{ Reset() }
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// NewCacheGroup creates a new empty CacheGroup
+func NewCacheGroup() *CacheGroup {
+	g := new(CacheGroup)
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+	groups.mux.Lock()
+	groups.m[g] = struct{}{}
+	groups.mux.Unlock()
+	return g
+}
+
+func (g *CacheGroup) add(c interface{ Reset() }) {
+	g.mux.Lock()
+	g.caches = append(g.caches, c)
+	g.mux.Unlock()
+}
+
+// Reset resets all caches of the group
+func (g *CacheGroup) Reset() {
+	g.mux.Lock()
+	caches := append([]interface{ Reset() }(nil), g.caches...)
+	g.mux.Unlock()
+
+	for _, c := range caches {
+		c.Reset()
+	}
+}
+
+// goRefresh runs the background update f with the context of the group. It returns false, if the group is closed.
+func (g *CacheGroup) goRefresh(f func(context.Context)) bool {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if g.closed {
+		return false
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		f(g.ctx)
+	}()
+	return true
+}
+
+// Close removes all caches from the group and releases the group, so that ResetCached() no longer reaches it.
+// Running background updates are cancelled and Close waits until they have returned.
+func (g *CacheGroup) Close() {
+	g.mux.Lock()
+	g.caches = nil
+	g.closed = true
+	g.mux.Unlock()
+
+	g.cancel()
+	g.wg.Wait()
+
+	groups.mux.Lock()
+	delete(groups.m, g)
+	groups.mux.Unlock()
+}
+
+// CacheOption is an option for ResettableCached and ResettableCachedCtx
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	group      *CacheGroup
+	serveStale bool
+	background bool
+	clock      clock.Clock
+}
+
+func newCacheOptions(opts []CacheOption) cacheOptions {
+	o := cacheOptions{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCacheClock sets the clock of the cache, e.g. clock.NewMock() for tests
+func WithCacheClock(clk clock.Clock) CacheOption {
+	return func(o *cacheOptions) {
+		o.clock = clk
+	}
+}
+
+// WithCacheGroup adds the cache to group, so that it is reset by group.Reset() and released by group.Close()
+func WithCacheGroup(group *CacheGroup) CacheOption {
+	return func(o *cacheOptions) {
+		o.group = group
+	}
+}
+
+// WithServeStale keeps the last successful value of the cache. If an update fails, this value is returned
+// together with a StaleError instead of the value returned by the failed getter.
+func WithServeStale() CacheOption {
+	return func(o *cacheOptions) {
+		o.serveStale = true
+	}
+}
+
+// WithBackgroundRefresh returns the last successful value at once when the cache has expired and updates it in the background
+// (stale-while-revalidate). Only the first update and the first update after Reset() block. It implies WithServeStale().
+// Background updates of caches with WithCacheGroup() are cancelled by CacheGroup.Close().
+func WithBackgroundRefresh() CacheOption {
+	return func(o *cacheOptions) {
+		o.serveStale = true
+		o.background = true
+	}
+}
+
+// StaleError is returned together with the last successful value of a cache in serve-stale mode, if the update failed.
+// Age is the time since the last successful update.
+type StaleError struct {
+	Err error
+	Age time.Duration
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("stale value (age %v): %v", e.Age.Round(time.Second), e.Err)
+}
+
+func (e *StaleError) Unwrap() error {
+	return e.Err
 }
 
 // cached wraps a getter with a cache
@@ -38,9 +196,18 @@ type cached[T any] struct {
 	retried        time.Time
 	cache          time.Duration
 	backoffCounter int
-	g              func() (T, error)
+	g              func(context.Context) (T, error)
 	val            T
 	err            error
+	group          *CacheGroup
+
+	serveStale bool
+	background bool
+	good       bool      // val is the result of a successful update
+	goodAt     time.Time // time of the last successful update
+	refreshing bool      // an update is running in the background
+	invalid    bool      // the cache was reset, so the next update must not run in the background
+	generation uint64    // incremented by Reset() and by every stored update
 }
 
 // Cached wraps a getter with a cache
@@ -52,6 +219,7 @@ func Cached[T any](g func() (T, error), cache time.Duration) func() (T, error) {
 // Cacheable is the interface for a resettable cache
 type Cacheable[T any] interface {
 	Get() (T, error)
+	GetCtx(ctx context.Context) (T, error)
 	Reset()
 }
 
@@ -59,38 +227,184 @@ var _ Cacheable[int64] = (*cached[int64])(nil)
 
 // ResettableCached wraps a getter with a cache. It returns a `Cacheable`.
 // Instead of the cached getter, the `Get()` and `Reset()` methods are exposed.
-func ResettableCached[T any](g func() (T, error), cache time.Duration) *cached[T] {
-	clock := clock.New()
+func ResettableCached[T any](g func() (T, error), cache time.Duration, opts ...CacheOption) *cached[T] {
+	return ResettableCachedCtx(func(context.Context) (T, error) {
+		return g()
+	}, cache, opts...)
+}
+
+// ResettableCachedCtx is like ResettableCached, but the getter receives the context
+// of the `GetCtx()` call that triggers the update.
+// Without WithCacheGroup(), the cache is reset by ResetCached() for the lifetime of the process.
+func ResettableCachedCtx[T any](g func(context.Context) (T, error), cache time.Duration, opts ...CacheOption) *cached[T] {
+	o := newCacheOptions(opts)
 	c := &cached[T]{
-		clock: clock,
-		cache: cache,
-		g:     g,
+		clock:      o.clock,
+		cache:      cache,
+		g:          g,
+		serveStale: o.serveStale,
+		background: o.background,
+		group:      o.group,
+	}
+	if o.group != nil {
+		o.group.add(c)
+	} else {
+		_ = bus.Subscribe(reset, c.Reset)
 	}
-	_ = bus.Subscribe(reset, c.Reset)
 	return c
 }
 
+// keyedCached holds a separate cache per key (e.g. per system), so that keys are updated,
+// reset and fail independently of each other.
+type keyedCached[T any] struct {
+	mux    sync.Mutex
+	caches map[string]*cached[T]
+	g      func(context.Context, string) (T, error)
+	check  func(context.Context, string) error
+	cache  time.Duration
+	opts   []CacheOption
+}
+
+// resettableKeyedCachedCtx wraps a getter with a key parameter with a cache per key.
+// If check is not nil, it is called for a key without cache. Keys rejected by check get no cache,
+// so that unknown keys do not fill the map.
+func resettableKeyedCachedCtx[T any](g func(context.Context, string) (T, error), check func(context.Context, string) error, cache time.Duration, opts ...CacheOption) *keyedCached[T] {
+	return &keyedCached[T]{
+		caches: make(map[string]*cached[T]),
+		g:      g,
+		check:  check,
+		cache:  cache,
+		opts:   opts,
+	}
+}
+
+// GetCtx returns the cached value of key
+func (k *keyedCached[T]) GetCtx(ctx context.Context, key string) (T, error) {
+	k.mux.Lock()
+	c, ok := k.caches[key]
+	k.mux.Unlock()
+
+	if !ok {
+		// check is called without lock, as it may request data
+		if k.check != nil {
+			if err := k.check(ctx, key); err != nil {
+				var zero T
+				return zero, err
+			}
+		}
+
+		k.mux.Lock()
+		if c, ok = k.caches[key]; !ok {
+			c = ResettableCachedCtx(func(ctx context.Context) (T, error) {
+				return k.g(ctx, key)
+			}, k.cache, k.opts...)
+			k.caches[key] = c
+		}
+		k.mux.Unlock()
+	}
+
+	return c.GetCtx(ctx)
+}
+
+// Reset resets the caches of all keys
+func (k *keyedCached[T]) Reset() {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	for _, c := range k.caches {
+		c.Reset()
+	}
+}
+
+// ResetKey resets the cache of key only
+func (k *keyedCached[T]) ResetKey(key string) {
+	k.mux.Lock()
+	c, ok := k.caches[key]
+	k.mux.Unlock()
+	if ok {
+		c.Reset()
+	}
+}
+
 func (c *cached[T]) Get() (T, error) {
+	return c.GetCtx(context.Background())
+}
+
+// GetCtx returns the cached value. If an update is necessary, ctx is passed to the getter.
+// An update aborted by ctx is not stored, so that other callers are not affected by it.
+func (c *cached[T]) GetCtx(ctx context.Context) (T, error) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	if c.mustUpdate() {
-		c.val, c.err = c.g()
-		c.updated = c.clock.Now()
-		c.retried = c.clock.Now()
-
-		if c.err == nil {
-			c.backoffCounter = 0
+	switch {
+	case c.refreshing && !c.invalid:
+		// the background update is still running, the current value is returned
+	case c.background && c.good && !c.invalid && c.mustUpdate():
+		c.refreshing = true
+		generation := c.generation
+		refresh := func(ctx context.Context) {
+			c.refresh(ctx, generation)
+		}
+		if c.group == nil {
+			go refresh(context.Background())
+		} else if !c.group.goRefresh(refresh) {
+			// the group is closed, the current value is kept
+			c.refreshing = false
+		}
+	case c.invalid || c.mustUpdate():
+		val, err := c.g(ctx)
+		if err != nil && ctx.Err() != nil {
+			return val, err
 		}
+		c.store(val, err)
 	}
 
+	if c.err != nil && c.serveStale && c.good {
+		return c.val, &StaleError{Err: c.err, Age: c.clock.Since(c.goodAt)}
+	}
 	return c.val, c.err
 }
 
+// refresh updates the cache in the background. An update cancelled by ctx is not stored.
+// The update is dropped, if generation has changed since its start, i.e. if the cache was reset or a newer
+// value has been stored in the meantime. After a Reset(), the next call of GetCtx() updates the cache.
+func (c *cached[T]) refresh(ctx context.Context, generation uint64) {
+	val, err := c.g(ctx)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.refreshing = false
+	if err != nil && ctx.Err() != nil || c.generation != generation {
+		return
+	}
+	c.store(val, err)
+}
+
+// store saves the result of an update. In serve-stale mode, the last successful value is kept on errors.
+func (c *cached[T]) store(val T, err error) {
+	c.err = err
+	c.updated = c.clock.Now()
+	c.retried = c.clock.Now()
+	c.invalid = false
+	c.generation++
+
+	if err == nil {
+		c.val = val
+		c.backoffCounter = 0
+		c.good = true
+		c.goodAt = c.updated
+		return
+	}
+	if !c.serveStale || !c.good {
+		c.val = val
+	}
+}
+
 func (c *cached[T]) Reset() {
 	c.mux.Lock()
 	c.updated = time.Time{}
 	c.retried = time.Time{}
+	c.invalid = true
+	c.generation++
 	c.mux.Unlock()
 }
 
@@ -119,9 +433,11 @@ type Value[T any] struct {
 	val     T
 }
 
-func NewValue[T any](cache time.Duration) *Value[T] {
+// NewValue creates a Value that expires after cache. Of the options, only WithCacheClock() is used.
+func NewValue[T any](cache time.Duration, opts ...CacheOption) *Value[T] {
+	o := newCacheOptions(opts)
 	return &Value[T]{
-		clock: clock.New(),
+		clock: o.clock,
 		cache: cache,
 	}
 }
@@ -0,0 +1,485 @@
+package sensonet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// counter is a getter that counts its calls
+type counter struct {
+	calls int
+}
+
+func (c *counter) get(context.Context) (int, error) {
+	c.calls++
+	return c.calls, nil
+}
+
+func TestCacheGroupReset(t *testing.T) {
+	clk := clock.NewMock()
+	g1, g2 := NewCacheGroup(), NewCacheGroup()
+	defer g1.Close()
+	defer g2.Close()
+
+	var n1, n2 counter
+	c1 := ResettableCachedCtx(n1.get, time.Hour, WithCacheGroup(g1), WithCacheClock(clk))
+	c2 := ResettableCachedCtx(n2.get, time.Hour, WithCacheGroup(g2), WithCacheClock(clk))
+
+	get := func() {
+		t.Helper()
+		if _, err := c1.Get(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c2.Get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get()
+	get()
+	if n1.calls != 1 || n2.calls != 1 {
+		t.Fatalf("calls before reset: got %d/%d, want 1/1", n1.calls, n2.calls)
+	}
+
+	// a reset of one group does not reach the other group
+	g1.Reset()
+	get()
+	if n1.calls != 2 || n2.calls != 1 {
+		t.Fatalf("calls after reset of group 1: got %d/%d, want 2/1", n1.calls, n2.calls)
+	}
+
+	// ResetCached reaches all open groups
+	ResetCached()
+	get()
+	if n1.calls != 3 || n2.calls != 2 {
+		t.Fatalf("calls after ResetCached: got %d/%d, want 3/2", n1.calls, n2.calls)
+	}
+}
+
+func TestCacheGroupClose(t *testing.T) {
+	clk := clock.NewMock()
+	g := NewCacheGroup()
+
+	var n counter
+	c := ResettableCachedCtx(n.get, time.Hour, WithCacheGroup(g), WithCacheClock(clk))
+	if _, err := c.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Close()
+
+	groups.mux.Lock()
+	_, open := groups.m[g]
+	groups.mux.Unlock()
+	if open {
+		t.Error("closed group is still registered")
+	}
+
+	// neither the closed group nor ResetCached reset the cache any more
+	g.Reset()
+	ResetCached()
+	if _, err := c.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if n.calls != 1 {
+		t.Errorf("calls after close: got %d, want 1", n.calls)
+	}
+}
+
+func TestKeyedCached(t *testing.T) {
+	clk := clock.NewMock()
+	g := NewCacheGroup()
+	defer g.Close()
+
+	calls := make(map[string]int)
+	get := func(_ context.Context, key string) (int, error) {
+		calls[key]++
+		return calls[key], nil
+	}
+	check := func(_ context.Context, key string) error {
+		if key == "unknown" {
+			return errors.New("unknown key")
+		}
+		return nil
+	}
+	k := resettableKeyedCachedCtx(get, check, time.Hour, WithCacheGroup(g), WithCacheClock(clk))
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "a", "b"} {
+		if _, err := k.GetCtx(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls["a"] != 1 || calls["b"] != 1 {
+		t.Fatalf("calls: got %v, want one per key", calls)
+	}
+
+	// keys rejected by check get no cache
+	if _, err := k.GetCtx(ctx, "unknown"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	k.mux.Lock()
+	_, ok := k.caches["unknown"]
+	k.mux.Unlock()
+	if ok || calls["unknown"] != 0 {
+		t.Error("unknown key was cached or requested")
+	}
+
+	// ResetKey resets a single key only
+	k.ResetKey("a")
+	if v, _ := k.GetCtx(ctx, "a"); v != 2 {
+		t.Errorf("a after ResetKey: got %d, want 2", v)
+	}
+	if v, _ := k.GetCtx(ctx, "b"); v != 1 {
+		t.Errorf("b after ResetKey of a: got %d, want 1", v)
+	}
+
+	// the caches of the keys expire independently of each other
+	clk.Add(30 * time.Minute)
+	k.ResetKey("b")
+	clk.Add(31 * time.Minute)
+	if v, _ := k.GetCtx(ctx, "a"); v != 3 {
+		t.Errorf("a after expiry: got %d, want 3", v)
+	}
+	if v, _ := k.GetCtx(ctx, "b"); v != 2 {
+		t.Errorf("b after reset: got %d, want 2", v)
+	}
+	if v, _ := k.GetCtx(ctx, "b"); v != 2 {
+		t.Errorf("b before expiry: got %d, want 2", v)
+	}
+
+	// the group reaches the caches of all keys
+	g.Reset()
+	if v, _ := k.GetCtx(ctx, "a"); v != 4 {
+		t.Errorf("a after group reset: got %d, want 4", v)
+	}
+	if v, _ := k.GetCtx(ctx, "b"); v != 3 {
+		t.Errorf("b after group reset: got %d, want 3", v)
+	}
+}
+
+// testGetter is a getter whose result is controlled by the test
+type testGetter struct {
+	mux   sync.Mutex
+	calls int
+	err   error
+	block chan struct{} // if not nil, the getter waits for it to be closed or for the cancellation of its context
+}
+
+func (g *testGetter) get(ctx context.Context) (int, error) {
+	g.mux.Lock()
+	g.calls++
+	calls, err, block := g.calls, g.err, g.block
+	g.mux.Unlock()
+
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return calls, err
+}
+
+func (g *testGetter) set(err error, block chan struct{}) {
+	g.mux.Lock()
+	g.err, g.block = err, block
+	g.mux.Unlock()
+}
+
+func (g *testGetter) count() int {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.calls
+}
+
+func TestCachedExpiryAndReset(t *testing.T) {
+	clk := clock.NewMock()
+	var g testGetter
+	c := ResettableCachedCtx(g.get, time.Minute, WithCacheGroup(NewCacheGroup()), WithCacheClock(clk))
+	defer c.group.Close()
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		reset   bool
+		want    int
+	}{
+		{"first update", 0, false, 1},
+		{"cached", 30 * time.Second, false, 1},
+		{"expired", 31 * time.Second, false, 2},
+		{"reset", 0, true, 3},
+		{"cached after reset", 59 * time.Second, false, 3},
+	}
+	for _, tc := range tests {
+		clk.Add(tc.advance)
+		if tc.reset {
+			c.Reset()
+		}
+		if v, err := c.Get(); err != nil || v != tc.want {
+			t.Errorf("%s: got %d, %v, want %d", tc.name, v, err, tc.want)
+		}
+	}
+}
+
+func TestCachedBackoff(t *testing.T) {
+	clk := clock.NewMock()
+	var g testGetter
+	g.set(errors.New("failed"), nil)
+	c := ResettableCachedCtx(g.get, time.Hour, WithCacheGroup(NewCacheGroup()), WithCacheClock(clk))
+	defer c.group.Close()
+
+	// the back-off doubles with every failed retry: 5s, 10s, 20s, ...
+	tests := []struct {
+		advance time.Duration
+		calls   int
+	}{
+		{0, 1},
+		{4 * time.Second, 1},
+		{2 * time.Second, 2},
+		{6 * time.Second, 2},
+		{5 * time.Second, 3},
+		{15 * time.Second, 3},
+		{6 * time.Second, 4},
+	}
+	for i, tc := range tests {
+		clk.Add(tc.advance)
+		if _, err := c.Get(); err == nil {
+			t.Fatalf("step %d: expected error", i)
+		}
+		if calls := g.count(); calls != tc.calls {
+			t.Errorf("step %d: got %d calls, want %d", i, calls, tc.calls)
+		}
+	}
+
+	// ErrMustRetry is retried at once
+	g.set(ErrMustRetry, nil)
+	clk.Add(time.Minute)
+	_, _ = c.Get()
+	_, _ = c.Get()
+	if calls := g.count(); calls != 6 {
+		t.Errorf("calls with ErrMustRetry: got %d, want 6", calls)
+	}
+}
+
+func TestCachedContextAbort(t *testing.T) {
+	clk := clock.NewMock()
+	var g testGetter
+	c := ResettableCachedCtx(g.get, time.Hour, WithCacheGroup(NewCacheGroup()), WithCacheClock(clk))
+	defer c.group.Close()
+
+	// an update aborted by the context of the caller is not stored
+	g.set(nil, make(chan struct{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	g.set(nil, nil)
+	if v, err := c.Get(); err != nil || v != 2 {
+		t.Errorf("got %d, %v, want 2 without error", v, err)
+	}
+}
+
+func TestCachedServeStale(t *testing.T) {
+	clk := clock.NewMock()
+	var g testGetter
+	c := ResettableCachedCtx(g.get, time.Minute, WithCacheGroup(NewCacheGroup()), WithCacheClock(clk), WithServeStale())
+	defer c.group.Close()
+
+	// without a successful update, there is nothing to serve
+	failed := errors.New("failed")
+	g.set(failed, nil)
+	if _, err := c.Get(); err != failed {
+		t.Fatalf("first update: got %v, want %v", err, failed)
+	}
+
+	g.set(nil, nil)
+	clk.Add(10 * time.Second)
+	if v, err := c.Get(); err != nil || v != 2 {
+		t.Fatalf("got %d, %v, want 2 without error", v, err)
+	}
+
+	g.set(failed, nil)
+	clk.Add(2 * time.Minute)
+	v, err := c.Get()
+	var staleErr *StaleError
+	if !errors.As(err, &staleErr) {
+		t.Fatalf("got %v, want StaleError", err)
+	}
+	if v != 2 || staleErr.Age != 2*time.Minute || !errors.Is(err, failed) {
+		t.Errorf("got %d, %v, want 2 with age 2m wrapping %v", v, err, failed)
+	}
+
+	// the stale value is served until an update succeeds
+	clk.Add(6 * time.Second)
+	if v, err := c.Get(); v != 2 || !errors.As(err, &staleErr) || staleErr.Age != 2*time.Minute+6*time.Second {
+		t.Errorf("got %d, %v, want 2 with age 2m6s", v, err)
+	}
+	g.set(nil, nil)
+	clk.Add(11 * time.Second)
+	if v, err := c.Get(); err != nil || v != 5 {
+		t.Errorf("got %d, %v, want 5 without error", v, err)
+	}
+}
+
+// waitFor polls cond until it is true or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestCachedBackgroundRefresh(t *testing.T) {
+	clk := clock.NewMock()
+	group := NewCacheGroup()
+	var g testGetter
+	c := ResettableCachedCtx(g.get, time.Minute, WithCacheGroup(group), WithCacheClock(clk), WithBackgroundRefresh())
+
+	// the first update blocks
+	if v, err := c.Get(); err != nil || v != 1 {
+		t.Fatalf("got %d, %v, want 1", v, err)
+	}
+
+	// an expired value is returned at once and updated in the background
+	block := make(chan struct{})
+	g.set(nil, block)
+	clk.Add(2 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if v, err := c.Get(); err != nil || v != 1 {
+			t.Fatalf("during refresh: got %d, %v, want 1", v, err)
+		}
+	}
+	waitFor(t, func() bool { return g.count() == 2 })
+
+	close(block)
+	waitFor(t, func() bool {
+		v, _ := c.Get()
+		return v == 2
+	})
+	if calls := g.count(); calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+
+	// after Reset(), the update blocks again
+	g.set(nil, nil)
+	c.Reset()
+	if v, err := c.Get(); err != nil || v != 3 {
+		t.Errorf("after reset: got %d, %v, want 3", v, err)
+	}
+
+	// Close cancels a running background update and waits for it, the cancelled update is not stored
+	g.set(nil, make(chan struct{}))
+	clk.Add(2 * time.Minute)
+	_, _ = c.Get()
+	waitFor(t, func() bool { return g.count() == 4 })
+
+	done := make(chan struct{})
+	go func() {
+		group.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	c.mux.Lock()
+	refreshing, val, err := c.refreshing, c.val, c.err
+	c.mux.Unlock()
+	if refreshing || val != 3 || err != nil {
+		t.Errorf("after close: refreshing %v, value %d, error %v, want false, 3, nil", refreshing, val, err)
+	}
+
+	// no background updates are started after Close
+	clk.Add(2 * time.Minute)
+	if v, _ := c.Get(); v != 3 || g.count() != 4 {
+		t.Errorf("after close: got %d with %d calls, want 3 with 4 calls", v, g.count())
+	}
+}
+
+func TestCachedBackgroundRefreshAfterReset(t *testing.T) {
+	clk := clock.NewMock()
+	group := NewCacheGroup()
+	defer group.Close()
+	var g testGetter
+	c := ResettableCachedCtx(g.get, time.Minute, WithCacheGroup(group), WithCacheClock(clk), WithBackgroundRefresh())
+
+	if v, err := c.Get(); err != nil || v != 1 {
+		t.Fatalf("got %d, %v, want 1", v, err)
+	}
+
+	refreshed := func() bool {
+		c.mux.Lock()
+		defer c.mux.Unlock()
+		return !c.refreshing
+	}
+
+	tests := []struct {
+		name      string
+		syncCalls int // synchronous updates after Reset() while the background update is running
+		want      int
+		calls     int
+	}{
+		// the result of the background update is older than the value of the synchronous update
+		{"reset and synchronous update", 1, 3, 3},
+		// the background update started before Reset(), so the next call updates the cache
+		{"reset without synchronous update", 0, 5, 5},
+	}
+	for _, tc := range tests {
+		block := make(chan struct{})
+		g.set(nil, block)
+		clk.Add(2 * time.Minute)
+		calls := g.count()
+		_, _ = c.Get()
+		waitFor(t, func() bool { return g.count() == calls+1 })
+
+		g.set(nil, nil)
+		c.Reset()
+		for i := 0; i < tc.syncCalls; i++ {
+			_, _ = c.Get()
+		}
+		close(block)
+		waitFor(t, refreshed)
+
+		if v, err := c.Get(); err != nil || v != tc.want {
+			t.Errorf("%s: got %d, %v, want %d", tc.name, v, err, tc.want)
+		}
+		if calls := g.count(); calls != tc.calls {
+			t.Errorf("%s: got %d calls, want %d", tc.name, calls, tc.calls)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	clk := clock.NewMock()
+	v := NewValue[int](time.Minute, WithCacheClock(clk))
+
+	if _, err := v.Get(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("unset value: got %v, want ErrTimeout", err)
+	}
+
+	v.Set(42)
+	clk.Add(time.Minute)
+	if val, err := v.Get(); err != nil || val != 42 {
+		t.Errorf("got %d, %v, want 42", val, err)
+	}
+
+	clk.Add(time.Second)
+	if _, err := v.Get(); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expired value: got %v, want ErrTimeout", err)
+	}
+}
@@ -2,11 +2,15 @@ package sensonet
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -14,23 +18,37 @@ import (
 
 // Connection is the Sensonet connection
 type Connection struct {
-	client *http.Client
+	client    *http.Client
+	endpoints Endpoints
+	profile   Profile
+	baseURL   string // overrides endpoints.ApiURLBase, if set
+	logger    Logger
+
+	mux                sync.Mutex
+	controlIdentifiers map[string]string
 }
 
 // NewConnection creates a new Sensonet device connection.
 func NewConnection(ts oauth2.TokenSource, opts ...ConnOption) (*Connection, error) {
 	conn := &Connection{
-		client: new(http.Client),
+		client:             new(http.Client),
+		endpoints:          EndpointsForBrand(BRAND_VAILLANT),
+		profile:            ProfileForBrand(BRAND_VAILLANT, ""),
+		controlIdentifiers: make(map[string]string),
 	}
 
 	for _, opt := range opts {
 		opt(conn)
 	}
+	if conn.baseURL != "" {
+		conn.endpoints.ApiURLBase = conn.baseURL
+	}
 
 	conn.client.Transport = &oauth2.Transport{
 		Source: ts,
 		Base: &transport{
-			conn.client.Transport,
+			RoundTripper: conn.client.Transport,
+			profile:      conn.profile,
 		},
 	}
 
@@ -39,32 +57,103 @@ func NewConnection(ts oauth2.TokenSource, opts ...ConnOption) (*Connection, erro
 
 // Returns all "homes" that belong to the current user under the myVaillant portal
 func (c *Connection) GetHomes() (Homes, error) {
+	return c.GetHomesCtx(context.Background())
+}
+
+// GetHomesCtx is like GetHomes, but the http requests are bound to ctx
+func (c *Connection) GetHomesCtx(ctx context.Context) (Homes, error) {
 	var res Homes
-	url := API_URL_BASE + "/homes"
-	req, _ := http.NewRequest("GET", url, nil)
+	url := c.endpoints.ApiURLBase + "/homes"
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	err := doJSON(c.client, req, &res)
 	return res, err
 }
 
+// Returns the control identifier of a specific systemId (CONTROL_IDENTIFIER_TLI or CONTROL_IDENTIFIER_VRC700).
+// The control identifier decides which urls and which operation modes are used for the system. It is requested only once per system.
+func (c *Connection) GetControlIdentifier(systemId string) (string, error) {
+	return c.GetControlIdentifierCtx(context.Background(), systemId)
+}
+
+// GetControlIdentifierCtx is like GetControlIdentifier, but the http requests are bound to ctx
+func (c *Connection) GetControlIdentifierCtx(ctx context.Context, systemId string) (string, error) {
+	c.mux.Lock()
+	controlIdentifier, ok := c.controlIdentifiers[systemId]
+	c.mux.Unlock()
+	if ok {
+		return controlIdentifier, nil
+	}
+
+	var res struct {
+		ControlIdentifier string `json:"controlIdentifier"`
+	}
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(CONTROLIDENTIFIER_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	err := doJSON(c.client, req, &res)
+
+	switch {
+	case err == nil && res.ControlIdentifier != "":
+		controlIdentifier = res.ControlIdentifier
+	case err == nil, isNotFound(err):
+		// systems without meta info are sensoCOMFORT/TLI systems
+		controlIdentifier = CONTROL_IDENTIFIER_TLI
+	default:
+		return "", err
+	}
+
+	c.mux.Lock()
+	c.controlIdentifiers[systemId] = controlIdentifier
+	c.mux.Unlock()
+	return controlIdentifier, nil
+}
+
+// systemURL returns the url of the system api of systemId (depending on its control identifier) followed by the formatted path
+func (c *Connection) systemURL(ctx context.Context, systemId string, format string, args ...any) (string, error) {
+	controlIdentifier, err := c.GetControlIdentifierCtx(ctx, systemId)
+	if err != nil {
+		return "", err
+	}
+	return c.endpoints.ApiURLBase + fmt.Sprintf(systemPath, systemId, controlIdentifier) + fmt.Sprintf(format, args...), nil
+}
+
 // Returns the system report (state, properties and configuration) for a specific systemId
 func (c *Connection) GetSystem(systemId string) (SystemStatus, error) {
+	return c.GetSystemCtx(context.Background(), systemId)
+}
+
+// GetSystemCtx is like GetSystem, but the http requests are bound to ctx
+func (c *Connection) GetSystemCtx(ctx context.Context, systemId string) (SystemStatus, error) {
 	var state SystemStatus
-	url := API_URL_BASE + fmt.Sprintf(SYSTEMS_URL, systemId)
-	req, _ := http.NewRequest("GET", url, nil)
-	err := doJSON(c.client, req, &state)
+	url, err := c.systemURL(ctx, systemId, "")
+	if err != nil {
+		return state, err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	err = doJSON(c.client, req, &state)
 	return state, err
 }
 
-// Returns the system devices for a specific systemId
+// Returns the system devices for a specific systemId. Firmware and online state of the gateway may be missing,
+// the Controller completes them from the homes.
 func (c *Connection) GetSystemDevices(systemId string) (SystemDevices, error) {
+	return c.GetSystemDevicesCtx(context.Background(), systemId)
+}
+
+// GetSystemDevicesCtx is like GetSystemDevices, but the http requests are bound to ctx
+func (c *Connection) GetSystemDevicesCtx(ctx context.Context, systemId string) (SystemDevices, error) {
 	var systemDevices SystemDevices
-	url := API_URL_BASE + fmt.Sprintf(DEVICES_URL, systemId)
-	req, _ := http.NewRequest("GET", url, nil)
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(DEVICES_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	err := doJSON(c.client, req, &systemDevices)
 	return systemDevices, err
 }
 
 func (c *Connection) StartZoneQuickVeto(systemId string, zone int, setpoint float32, duration float32) error {
+	return c.StartZoneQuickVetoCtx(context.Background(), systemId, zone, setpoint, duration)
+}
+
+// StartZoneQuickVetoCtx is like StartZoneQuickVeto, but the http requests are bound to ctx
+func (c *Connection) StartZoneQuickVetoCtx(ctx context.Context, systemId string, zone int, setpoint float32, duration float32) error {
 	if zone < 0 {
 		zone = ZONEINDEX_DEFAULT
 	} // if parameter "zone" is negative, then the default value is used
@@ -74,13 +163,16 @@ func (c *Connection) StartZoneQuickVeto(systemId string, zone int, setpoint floa
 	if duration < 0.0 {
 		duration = ZONEVETODURATION_DEFAULT
 	} // if parameter "duration" is negative, then the default value is used
-	url := API_URL_BASE + fmt.Sprintf(ZONEQUICKVETO_URL, systemId, zone)
+	url, err := c.systemURL(ctx, systemId, zoneQuickVetoPath, zone)
+	if err != nil {
+		return err
+	}
 	data := map[string]float32{
 		"desiredRoomTemperatureSetpoint": setpoint,
 		"duration":                       duration,
 	}
 	b, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(b))
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
 
 	if _, err := doBody(c.client, req); err != nil {
@@ -90,12 +182,20 @@ func (c *Connection) StartZoneQuickVeto(systemId string, zone int, setpoint floa
 }
 
 func (c *Connection) StopZoneQuickVeto(systemId string, zone int) error {
+	return c.StopZoneQuickVetoCtx(context.Background(), systemId, zone)
+}
+
+// StopZoneQuickVetoCtx is like StopZoneQuickVeto, but the http requests are bound to ctx
+func (c *Connection) StopZoneQuickVetoCtx(ctx context.Context, systemId string, zone int) error {
 	if zone < 0 {
 		zone = ZONEINDEX_DEFAULT
 	} // if parameter "zone" is negative, then the default value is used
 
-	url := API_URL_BASE + fmt.Sprintf(ZONEQUICKVETO_URL, systemId, zone)
-	req, _ := http.NewRequest("DELETE", url, nil)
+	url, err := c.systemURL(ctx, systemId, zoneQuickVetoPath, zone)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 
 	if _, err := doBody(c.client, req); err != nil {
 		return err
@@ -104,12 +204,20 @@ func (c *Connection) StopZoneQuickVeto(systemId string, zone int) error {
 }
 
 func (c *Connection) StartHotWaterBoost(systemId string, hotwaterIndex int) error {
+	return c.StartHotWaterBoostCtx(context.Background(), systemId, hotwaterIndex)
+}
+
+// StartHotWaterBoostCtx is like StartHotWaterBoost, but the http requests are bound to ctx
+func (c *Connection) StartHotWaterBoostCtx(ctx context.Context, systemId string, hotwaterIndex int) error {
 	if hotwaterIndex < 0 {
 		hotwaterIndex = HOTWATERINDEX_DEFAULT
 	} // if parameter "hotwaterIndex" is negative, then the default value is used
 
-	url := API_URL_BASE + fmt.Sprintf(HOTWATERBOOST_URL, systemId, hotwaterIndex)
-	req, _ := http.NewRequest("POST", url, strings.NewReader("{}"))
+	url, err := c.systemURL(ctx, systemId, hotWaterBoostPath, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader("{}"))
 	req.Header.Set("Content-Type", "application/json")
 
 	if _, err := doBody(c.client, req); err != nil {
@@ -119,12 +227,20 @@ func (c *Connection) StartHotWaterBoost(systemId string, hotwaterIndex int) erro
 }
 
 func (c *Connection) StopHotWaterBoost(systemId string, hotwaterIndex int) error {
+	return c.StopHotWaterBoostCtx(context.Background(), systemId, hotwaterIndex)
+}
+
+// StopHotWaterBoostCtx is like StopHotWaterBoost, but the http requests are bound to ctx
+func (c *Connection) StopHotWaterBoostCtx(ctx context.Context, systemId string, hotwaterIndex int) error {
 	if hotwaterIndex < 0 {
 		hotwaterIndex = HOTWATERINDEX_DEFAULT
 	} // if parameter "hotwaterIndex" is negative, then the default value is used
 
-	url := API_URL_BASE + fmt.Sprintf(HOTWATERBOOST_URL, systemId, hotwaterIndex)
-	req, _ := http.NewRequest("DELETE", url, nil)
+	url, err := c.systemURL(ctx, systemId, hotWaterBoostPath, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 
 	if _, err := doBody(c.client, req); err != nil {
 		return err
@@ -134,35 +250,26 @@ func (c *Connection) StopHotWaterBoost(systemId string, hotwaterIndex int) error
 
 // Returns the device data for given criteria
 func (c *Connection) GetDeviceData(systemId string, whichDevices int) ([]DeviceAndInfo, error) {
+	return c.GetDeviceDataCtx(context.Background(), systemId, whichDevices)
+}
+
+// GetDeviceDataCtx is like GetDeviceData, but the http requests are bound to ctx
+func (c *Connection) GetDeviceDataCtx(ctx context.Context, systemId string, whichDevices int) ([]DeviceAndInfo, error) {
 	var devices []DeviceAndInfo
-	systemDevices, err := c.GetSystemDevices(systemId)
+	systemDevices, err := c.GetSystemDevicesCtx(ctx, systemId)
 	if err != nil {
 		return devices, err
 	}
-	var deviceAndInfo DeviceAndInfo
-	if systemDevices.PrimaryHeatGenerator.DeviceUUID != "" && (whichDevices == DEVICES_PRIMARY_HEATER || whichDevices == DEVICES_ALL) {
-		deviceAndInfo.Device = systemDevices.PrimaryHeatGenerator
-		deviceAndInfo.Info = "primary_heat_generator"
-		devices = append(devices, deviceAndInfo)
-	}
-	if whichDevices == DEVICES_SECONDARY_HEATER || whichDevices == DEVICES_ALL {
-		for _, secHeatGen := range systemDevices.SecondaryHeatGenerators {
-			deviceAndInfo.Device = secHeatGen
-			deviceAndInfo.Info = "secondary_heat_generator"
-			devices = append(devices, deviceAndInfo)
-		}
-	}
-
-	if systemDevices.ElectricBackupHeater.DeviceUUID != "" && (whichDevices == DEVICES_BACKUP_HEATER || whichDevices == DEVICES_ALL) {
-		deviceAndInfo.Device = systemDevices.ElectricBackupHeater
-		deviceAndInfo.Info = "electric_backup_heater"
-		devices = append(devices, deviceAndInfo)
-	}
-	return devices, nil
+	return GetDevicesAndInfo(systemDevices, whichDevices), nil
 }
 
 // Returns the energy data for systemId, deviceUuid and other given criteria
 func (c *Connection) GetEnergyData(systemId, deviceUuid, operationMode, energyType, resolution string, startDate, endDate time.Time) (EnergyData, error) {
+	return c.GetEnergyDataCtx(context.Background(), systemId, deviceUuid, operationMode, energyType, resolution, startDate, endDate)
+}
+
+// GetEnergyDataCtx is like GetEnergyData, but the http requests are bound to ctx
+func (c *Connection) GetEnergyDataCtx(ctx context.Context, systemId, deviceUuid, operationMode, energyType, resolution string, startDate, endDate time.Time) (EnergyData, error) {
 	var energyData EnergyData
 	v := url.Values{
 		"resolution":    {resolution},
@@ -172,8 +279,8 @@ func (c *Connection) GetEnergyData(systemId, deviceUuid, operationMode, energyTy
 		"endDate":       {endDate.Format("2006-01-02T15:04:05-07:00")},
 	}
 
-	url := API_URL_BASE + fmt.Sprintf(ENERGY_URL, systemId, deviceUuid) + v.Encode()
-	req, _ := http.NewRequest("GET", url, nil)
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(ENERGY_URL, systemId, deviceUuid) + v.Encode()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err := doJSON(c.client, req, &energyData); err != nil {
 		return energyData, err
 	}
@@ -182,19 +289,207 @@ func (c *Connection) GetEnergyData(systemId, deviceUuid, operationMode, energyTy
 
 // Returns the mpc data for systemId
 func (c *Connection) GetMpcData(systemId string) ([]MpcDevice, error) {
+	return c.GetMpcDataCtx(context.Background(), systemId)
+}
+
+// GetMpcDataCtx is like GetMpcData, but the http requests are bound to ctx
+func (c *Connection) GetMpcDataCtx(ctx context.Context, systemId string) ([]MpcDevice, error) {
 	var mpcData MpcData
 
-	url := API_URL_BASE + fmt.Sprintf(MPC_URL, systemId)
-	req, _ := http.NewRequest("GET", url, nil)
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(MPC_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err := doJSON(c.client, req, &mpcData); err != nil {
 		return mpcData.Devices, err
 	}
 	return mpcData.Devices, nil
 }
 
+// Returns the ambisense rooms of systemId. Systems without ambisense radiator thermostats have no rooms.
+func (c *Connection) GetRooms(systemId string) ([]Room, error) {
+	return c.GetRoomsCtx(context.Background(), systemId)
+}
+
+// GetRoomsCtx is like GetRooms, but the http requests are bound to ctx
+func (c *Connection) GetRoomsCtx(ctx context.Context, systemId string) ([]Room, error) {
+	var rooms []Room
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(ROOMS_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err := doJSON(c.client, req, &rooms); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// Sets the time program of an ambisense room
+func (c *Connection) SetRoomTimeProgram(systemId string, room int, timeProgram RoomTimeProgram) error {
+	return c.SetRoomTimeProgramCtx(context.Background(), systemId, room, timeProgram)
+}
+
+// SetRoomTimeProgramCtx is like SetRoomTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetRoomTimeProgramCtx(ctx context.Context, systemId string, room int, timeProgram RoomTimeProgram) error {
+	if err := timeProgram.Validate(); err != nil {
+		return err
+	}
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(ROOMTIMEPROGRAM_URL, systemId, room)
+	return c.sendJSON(ctx, "PUT", url, timeProgram)
+}
+
+// Starts a quick veto for an ambisense room. Setpoint is the temperature in °C, duration is given in hours.
+func (c *Connection) StartRoomQuickVeto(systemId string, room int, setpoint float32, duration float32) error {
+	return c.StartRoomQuickVetoCtx(context.Background(), systemId, room, setpoint, duration)
+}
+
+// StartRoomQuickVetoCtx is like StartRoomQuickVeto, but the http requests are bound to ctx
+func (c *Connection) StartRoomQuickVetoCtx(ctx context.Context, systemId string, room int, setpoint float32, duration float32) error {
+	if setpoint < 0.0 {
+		setpoint = ZONEVETOSETPOINT_DEFAULT
+	} // if parameter "setpoint" is negative, then the default value is used
+	if duration < 0.0 {
+		duration = ZONEVETODURATION_DEFAULT
+	} // if parameter "duration" is negative, then the default value is used
+	if err := checkRange("room setpoint", float64(setpoint), ROOMSETPOINT_MIN, ROOMSETPOINT_MAX); err != nil {
+		return err
+	}
+
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(ROOMQUICKVETO_URL, systemId, room)
+	data := map[string]any{
+		"temperatureSetpoint": setpoint,
+		"duration":            int(duration * 60), // the api expects minutes
+	}
+	return c.sendJSON(ctx, "PUT", url, data)
+}
+
+// Stops the quick veto of an ambisense room
+func (c *Connection) StopRoomQuickVeto(systemId string, room int) error {
+	return c.StopRoomQuickVetoCtx(context.Background(), systemId, room)
+}
+
+// StopRoomQuickVetoCtx is like StopRoomQuickVeto, but the http requests are bound to ctx
+func (c *Connection) StopRoomQuickVetoCtx(ctx context.Context, systemId string, room int) error {
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(ROOMQUICKVETO_URL, systemId, room)
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	_, err := doBody(c.client, req)
+	return err
+}
+
+// Returns the active status, error and maintenance codes of all devices of systemId
+func (c *Connection) GetDiagnostics(systemId string) ([]Diagnostic, error) {
+	return c.GetDiagnosticsCtx(context.Background(), systemId)
+}
+
+// GetDiagnosticsCtx is like GetDiagnostics, but the http requests are bound to ctx
+func (c *Connection) GetDiagnosticsCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	var devices []deviceTroubleCodes
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(DIAGNOSTICS_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err := doJSON(c.client, req, &devices); err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for _, device := range devices {
+		for _, code := range device.Codes {
+			diagnostics = append(diagnostics, Diagnostic{
+				Type:         diagnosticType(code.CodeType),
+				DeviceID:     device.DeviceID,
+				SerialNumber: device.SerialNumber,
+				Code:         fmt.Sprintf("%s.%d", code.CodeType, code.CodeNumber),
+				Description:  code.Description,
+				Timestamp:    code.OccurrenceTimestamp,
+			})
+		}
+	}
+	return diagnostics, nil
+}
+
+// Returns the active maintenance codes of all devices of systemId
+func (c *Connection) GetMaintenance(systemId string) ([]Diagnostic, error) {
+	return c.GetMaintenanceCtx(context.Background(), systemId)
+}
+
+// GetMaintenanceCtx is like GetMaintenance, but the http requests are bound to ctx
+func (c *Connection) GetMaintenanceCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	diagnostics, err := c.GetDiagnosticsCtx(ctx, systemId)
+	return FilterDiagnostics(diagnostics, DIAGNOSTIC_TYPE_MAINTENANCE), err
+}
+
+// Returns the notification history of systemId. Systems without notifications return an empty list.
+func (c *Connection) GetNotifications(systemId string) ([]Diagnostic, error) {
+	return c.GetNotificationsCtx(context.Background(), systemId)
+}
+
+// GetNotificationsCtx is like GetNotifications, but the http requests are bound to ctx
+func (c *Connection) GetNotificationsCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	var notifications []notification
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(NOTIFICATIONS_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err := doJSON(c.client, req, &notifications); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	diagnostics := make([]Diagnostic, 0, len(notifications))
+	for _, n := range notifications {
+		description := n.Title
+		if n.Message != "" {
+			description = strings.TrimSpace(n.Title + ": " + n.Message)
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Type:        DIAGNOSTIC_TYPE_NOTIFICATION,
+			DeviceID:    n.DeviceID,
+			Code:        n.Code,
+			Description: description,
+			Timestamp:   n.Timestamp,
+		})
+	}
+	return diagnostics, nil
+}
+
+// diagnosticType maps the code type of the API ("F", "S", "M") to a DiagnosticType
+func diagnosticType(codeType string) DiagnosticType {
+	switch strings.ToUpper(codeType) {
+	case "F":
+		return DIAGNOSTIC_TYPE_ERROR
+	case "M":
+		return DIAGNOSTIC_TYPE_MAINTENANCE
+	default:
+		return DIAGNOSTIC_TYPE_STATUS
+	}
+}
+
+// Returns the live report (current sensor values like tank, flow and return temperatures) for systemId.
+// Systems without live report return an empty report.
+func (c *Connection) GetLiveReport(systemId string) (LiveReport, error) {
+	return c.GetLiveReportCtx(context.Background(), systemId)
+}
+
+// GetLiveReportCtx is like GetLiveReport, but the http requests are bound to ctx
+func (c *Connection) GetLiveReportCtx(ctx context.Context, systemId string) (LiveReport, error) {
+	var liveReport LiveReport
+	url := c.endpoints.ApiURLBase + fmt.Sprintf(LIVEREPORT_URL, systemId)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err := doJSON(c.client, req, &liveReport); err != nil {
+		if isNotFound(err) {
+			return LiveReport{}, nil
+		}
+		return LiveReport{}, err
+	}
+	return liveReport, nil
+}
+
 // Returns the current power consumption for systemId
 func (c *Connection) GetSystemCurrentPower(systemId string) (float64, error) {
-	mpcDevices, err := c.GetMpcData(systemId)
+	return c.GetSystemCurrentPowerCtx(context.Background(), systemId)
+}
+
+// GetSystemCurrentPowerCtx is like GetSystemCurrentPower, but the http requests are bound to ctx
+func (c *Connection) GetSystemCurrentPowerCtx(ctx context.Context, systemId string) (float64, error) {
+	mpcDevices, err := c.GetMpcDataCtx(ctx, systemId)
 	if err != nil || len(mpcDevices) < 1 {
 		return -1.0, err
 	}
@@ -207,15 +502,20 @@ func (c *Connection) GetSystemCurrentPower(systemId string) (float64, error) {
 
 // Returns the current power consumption and product name for deviceUuid. If "All" is given as deviceUuid, then the function return the power consumption and product name for all devices of systemId
 func (c *Connection) GetDeviceCurrentPower(systemId, deviceUuid string) (DevicePowerMap, error) {
+	return c.GetDeviceCurrentPowerCtx(context.Background(), systemId, deviceUuid)
+}
+
+// GetDeviceCurrentPowerCtx is like GetDeviceCurrentPower, but the http requests are bound to ctx
+func (c *Connection) GetDeviceCurrentPowerCtx(ctx context.Context, systemId, deviceUuid string) (DevicePowerMap, error) {
 	devicePowerMap := make(DevicePowerMap)
 	if deviceUuid == "All" {
 		devicePowerMap["All"] = DevicePower{CurrentPower: -1.0, ProductName: "All Devices"}
 	}
-	mpcDevices, err := c.GetMpcData(systemId)
+	mpcDevices, err := c.GetMpcDataCtx(ctx, systemId)
 	if err != nil || len(mpcDevices) < 1 {
 		return devicePowerMap, err
 	}
-	devices, err := c.GetDeviceData(systemId, DEVICES_ALL)
+	devices, err := c.GetDeviceDataCtx(ctx, systemId, DEVICES_ALL)
 	if err != nil {
 		return devicePowerMap, err
 	}
@@ -233,3 +533,691 @@ func (c *Connection) GetDeviceCurrentPower(systemId, deviceUuid string) (DeviceP
 	devicePowerMap["All"] = DevicePower{CurrentPower: totalPower, ProductName: "All Devices"}
 	return devicePowerMap, nil
 }
+
+// Sets the heating time program of a zone. The time program is validated against its MetaInfo before it is sent.
+// The Connection does not load the MetaInfo of the zone, so the limits are only checked if the caller has set
+// timeProgram.MetaInfo, e.g. from the time program returned by GetSystem(). Controller.SetZoneTimeProgram() always
+// validates against the MetaInfo of the zone.
+func (c *Connection) SetZoneTimeProgram(systemId string, zone int, timeProgram TimeProgram) error {
+	return c.SetZoneTimeProgramCtx(context.Background(), systemId, zone, timeProgram)
+}
+
+// SetZoneTimeProgramCtx is like SetZoneTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetZoneTimeProgramCtx(ctx context.Context, systemId string, zone int, timeProgram TimeProgram) error {
+	return c.setZoneTimeProgram(ctx, systemId, zone, TIMEPROGRAM_TYPE_HEATING, timeProgram)
+}
+
+// Sets the cooling time program of a zone. The time program is validated against its MetaInfo before it is sent.
+// As for SetZoneTimeProgram, the limits are only checked if the caller has set timeProgram.MetaInfo.
+func (c *Connection) SetZoneCoolingTimeProgram(systemId string, zone int, timeProgram TimeProgram) error {
+	return c.SetZoneCoolingTimeProgramCtx(context.Background(), systemId, zone, timeProgram)
+}
+
+// SetZoneCoolingTimeProgramCtx is like SetZoneCoolingTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetZoneCoolingTimeProgramCtx(ctx context.Context, systemId string, zone int, timeProgram TimeProgram) error {
+	return c.setZoneTimeProgram(ctx, systemId, zone, TIMEPROGRAM_TYPE_COOLING, timeProgram)
+}
+
+func (c *Connection) setZoneTimeProgram(ctx context.Context, systemId string, zone int, programType string, timeProgram TimeProgram) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if err := timeProgram.Validate(); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, ZONETIMEPROGRAM_URL, zone)
+	if err != nil {
+		return err
+	}
+	data := timeProgramData(timeProgram)
+	data["type"] = programType
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the time program of the domestic hot water. It works for both, systems with "dhw" and with "domesticHotWater".
+// The time program is validated against timeProgram.MetaInfo, which the Connection does not load. Use
+// Controller.SetHotWaterTimeProgram() to validate against the MetaInfo of the current time program.
+func (c *Connection) SetHotWaterTimeProgram(systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.SetHotWaterTimeProgramCtx(context.Background(), systemId, hotwaterIndex, timeProgram)
+}
+
+// SetHotWaterTimeProgramCtx is like SetHotWaterTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetHotWaterTimeProgramCtx(ctx context.Context, systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.setHotWaterTimeProgram(ctx, HOTWATERTIMEPROGRAM_URL, systemId, hotwaterIndex, timeProgram)
+}
+
+// Sets the time program of the circulation pump. It works for both, systems with "dhw" and with "domesticHotWater".
+// As for SetHotWaterTimeProgram, the limits are only checked if the caller has set timeProgram.MetaInfo.
+func (c *Connection) SetCirculationPumpTimeProgram(systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.SetCirculationPumpTimeProgramCtx(context.Background(), systemId, hotwaterIndex, timeProgram)
+}
+
+// SetCirculationPumpTimeProgramCtx is like SetCirculationPumpTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetCirculationPumpTimeProgramCtx(ctx context.Context, systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.setHotWaterTimeProgram(ctx, CIRCULATIONPUMPTIMEPROGRAM_URL, systemId, hotwaterIndex, timeProgram)
+}
+
+func (c *Connection) setHotWaterTimeProgram(ctx context.Context, urlFormat, systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	if hotwaterIndex < 0 {
+		hotwaterIndex = HOTWATERINDEX_DEFAULT
+	} // if parameter "hotwaterIndex" is negative, then the default value is used
+	if err := timeProgram.Validate(); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, urlFormat, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	return c.sendJSON(ctx, "PATCH", url, timeProgramData(timeProgram))
+}
+
+// Sets the heating operation mode of a zone (OPERATIONMODE_OFF, OPERATIONMODE_MANUAL or OPERATIONMODE_TIME_CONTROLLED,
+// for VRC700 systems OPERATIONMODE_OFF, OPERATIONMODE_DAY, OPERATIONMODE_AUTO or OPERATIONMODE_SET_BACK)
+func (c *Connection) SetZoneOperationMode(systemId string, zone int, mode OperationMode) error {
+	return c.SetZoneOperationModeCtx(context.Background(), systemId, zone, mode)
+}
+
+// SetZoneOperationModeCtx is like SetZoneOperationMode, but the http requests are bound to ctx
+func (c *Connection) SetZoneOperationModeCtx(ctx context.Context, systemId string, zone int, mode OperationMode) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	controlIdentifier, err := c.GetControlIdentifierCtx(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	if err := checkOperationMode(c.operationModes(zoneOperationModes, controlIdentifier), mode); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, ZONEOPERATIONMODE_URL, zone)
+	if err != nil {
+		return err
+	}
+	// VRC700 systems expect the plain key "operationMode"
+	key := "operationModeHeating"
+	if controlIdentifier == CONTROL_IDENTIFIER_VRC700 {
+		key = "operationMode"
+	}
+	data := map[string]OperationMode{
+		key: mode,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the cooling operation mode of a zone (OPERATIONMODE_OFF, OPERATIONMODE_MANUAL or OPERATIONMODE_TIME_CONTROLLED,
+// for VRC700 systems OPERATIONMODE_OFF, OPERATIONMODE_DAY or OPERATIONMODE_AUTO)
+func (c *Connection) SetZoneCoolingOperationMode(systemId string, zone int, mode OperationMode) error {
+	return c.SetZoneCoolingOperationModeCtx(context.Background(), systemId, zone, mode)
+}
+
+// SetZoneCoolingOperationModeCtx is like SetZoneCoolingOperationMode, but the http requests are bound to ctx
+func (c *Connection) SetZoneCoolingOperationModeCtx(ctx context.Context, systemId string, zone int, mode OperationMode) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	controlIdentifier, err := c.GetControlIdentifierCtx(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	if err := checkOperationMode(c.operationModes(coolingOperationModes, controlIdentifier), mode); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, ZONECOOLINGOPERATIONMODE_URL, zone)
+	if err != nil {
+		return err
+	}
+	data := map[string]OperationMode{
+		"operationModeCooling": mode,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the operation mode of the domestic hot water (OPERATIONMODE_OFF, OPERATIONMODE_MANUAL or OPERATIONMODE_TIME_CONTROLLED,
+// for VRC700 systems OPERATIONMODE_OFF, OPERATIONMODE_DAY or OPERATIONMODE_AUTO)
+func (c *Connection) SetHotWaterOperationMode(systemId string, hotwaterIndex int, mode OperationMode) error {
+	return c.SetHotWaterOperationModeCtx(context.Background(), systemId, hotwaterIndex, mode)
+}
+
+// SetHotWaterOperationModeCtx is like SetHotWaterOperationMode, but the http requests are bound to ctx
+func (c *Connection) SetHotWaterOperationModeCtx(ctx context.Context, systemId string, hotwaterIndex int, mode OperationMode) error {
+	if hotwaterIndex < 0 {
+		hotwaterIndex = HOTWATERINDEX_DEFAULT
+	} // if parameter "hotwaterIndex" is negative, then the default value is used
+	controlIdentifier, err := c.GetControlIdentifierCtx(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	if err := checkOperationMode(c.operationModes(hotWaterOperationModes, controlIdentifier), mode); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, HOTWATEROPERATIONMODE_URL, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	data := map[string]OperationMode{
+		"operationMode": mode,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the tapping setpoint of the domestic hot water. The setpoint is not checked against the limits of the system.
+func (c *Connection) SetHotWaterSetpoint(systemId string, hotwaterIndex int, setpoint float64) error {
+	return c.SetHotWaterSetpointCtx(context.Background(), systemId, hotwaterIndex, setpoint)
+}
+
+// SetHotWaterSetpointCtx is like SetHotWaterSetpoint, but the http requests are bound to ctx
+func (c *Connection) SetHotWaterSetpointCtx(ctx context.Context, systemId string, hotwaterIndex int, setpoint float64) error {
+	if hotwaterIndex < 0 {
+		hotwaterIndex = HOTWATERINDEX_DEFAULT
+	} // if parameter "hotwaterIndex" is negative, then the default value is used
+
+	url, err := c.systemURL(ctx, systemId, HOTWATERSETPOINT_URL, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	data := map[string]float64{
+		"setpoint": setpoint,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the setpoint of a zone for the operation mode OPERATIONMODE_MANUAL.
+// If the setpoint is outside of ZONESETPOINT_MIN and ZONESETPOINT_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetZoneManualModeSetpoint(systemId string, zone int, setpoint float64) error {
+	return c.SetZoneManualModeSetpointCtx(context.Background(), systemId, zone, setpoint)
+}
+
+// SetZoneManualModeSetpointCtx is like SetZoneManualModeSetpoint, but the http requests are bound to ctx
+func (c *Connection) SetZoneManualModeSetpointCtx(ctx context.Context, systemId string, zone int, setpoint float64) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if err := checkRange("manual mode setpoint", setpoint, ZONESETPOINT_MIN, ZONESETPOINT_MAX); err != nil {
+		return err
+	}
+
+	return c.setZoneSetpoint(ctx, systemId, zone, SETPOINT_TYPE_HEATING, setpoint)
+}
+
+// Sets the cooling setpoint of a zone.
+// If the setpoint is outside of ZONESETPOINT_MIN and ZONESETPOINT_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetZoneCoolingSetpoint(systemId string, zone int, setpoint float64) error {
+	return c.SetZoneCoolingSetpointCtx(context.Background(), systemId, zone, setpoint)
+}
+
+// SetZoneCoolingSetpointCtx is like SetZoneCoolingSetpoint, but the http requests are bound to ctx
+func (c *Connection) SetZoneCoolingSetpointCtx(ctx context.Context, systemId string, zone int, setpoint float64) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if err := checkRange("cooling setpoint", setpoint, ZONESETPOINT_MIN, ZONESETPOINT_MAX); err != nil {
+		return err
+	}
+	return c.setZoneSetpoint(ctx, systemId, zone, SETPOINT_TYPE_COOLING, setpoint)
+}
+
+// Starts a quick veto with setpoint as cooling target.
+// If the setpoint is outside of ZONESETPOINT_MIN and ZONESETPOINT_MAX, an OutOfRangeError is returned.
+func (c *Connection) StartZoneCoolingQuickVeto(systemId string, zone int, setpoint float32, duration float32) error {
+	return c.StartZoneCoolingQuickVetoCtx(context.Background(), systemId, zone, setpoint, duration)
+}
+
+// StartZoneCoolingQuickVetoCtx is like StartZoneCoolingQuickVeto, but the http requests are bound to ctx
+func (c *Connection) StartZoneCoolingQuickVetoCtx(ctx context.Context, systemId string, zone int, setpoint float32, duration float32) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if duration < 0.0 {
+		duration = ZONEVETODURATION_DEFAULT
+	} // if parameter "duration" is negative, then the default value is used
+	if err := checkRange("cooling quick veto setpoint", float64(setpoint), ZONESETPOINT_MIN, ZONESETPOINT_MAX); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, zoneQuickVetoPath, zone)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{
+		"desiredRoomTemperatureSetpoint": setpoint,
+		"duration":                       duration,
+		"type":                           SETPOINT_TYPE_COOLING,
+	}
+	return c.sendJSON(ctx, "POST", url, data)
+}
+
+func (c *Connection) setZoneSetpoint(ctx context.Context, systemId string, zone int, setpointType string, setpoint float64) error {
+	url, err := c.systemURL(ctx, systemId, ZONEMANUALMODESETPOINT_URL, zone)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{
+		"setpoint": setpoint,
+		"type":     setpointType,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the set-back temperature of a zone, which is used outside of the slots of the time program.
+// If the temperature is outside of ZONESETPOINT_MIN and ZONESETPOINT_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetZoneSetBackTemperature(systemId string, zone int, temperature float64) error {
+	return c.SetZoneSetBackTemperatureCtx(context.Background(), systemId, zone, temperature)
+}
+
+// SetZoneSetBackTemperatureCtx is like SetZoneSetBackTemperature, but the http requests are bound to ctx
+func (c *Connection) SetZoneSetBackTemperatureCtx(ctx context.Context, systemId string, zone int, temperature float64) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if err := checkRange("set-back temperature", temperature, ZONESETPOINT_MIN, ZONESETPOINT_MAX); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, ZONESETBACKTEMPERATURE_URL, zone)
+	if err != nil {
+		return err
+	}
+	data := map[string]float64{
+		"setBackTemperature": temperature,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the heating curve of a heating circuit.
+// If the value is outside of HEATINGCURVE_MIN and HEATINGCURVE_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetCircuitHeatingCurve(systemId string, circuit int, heatingCurve float64) error {
+	return c.SetCircuitHeatingCurveCtx(context.Background(), systemId, circuit, heatingCurve)
+}
+
+// SetCircuitHeatingCurveCtx is like SetCircuitHeatingCurve, but the http requests are bound to ctx
+func (c *Connection) SetCircuitHeatingCurveCtx(ctx context.Context, systemId string, circuit int, heatingCurve float64) error {
+	if err := checkRange("heating curve", heatingCurve, HEATINGCURVE_MIN, HEATINGCURVE_MAX); err != nil {
+		return err
+	}
+	return c.setCircuitValue(ctx, CIRCUITHEATINGCURVE_URL, systemId, circuit, "heatingCurve", heatingCurve)
+}
+
+// Sets the minimum flow temperature setpoint of a heating circuit.
+// If the value is outside of FLOWTEMPERATURE_MIN and FLOWTEMPERATURE_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetCircuitMinFlowTemperature(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitMinFlowTemperatureCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitMinFlowTemperatureCtx is like SetCircuitMinFlowTemperature, but the http requests are bound to ctx
+func (c *Connection) SetCircuitMinFlowTemperatureCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	if err := checkRange("minimum flow temperature", temperature, FLOWTEMPERATURE_MIN, FLOWTEMPERATURE_MAX); err != nil {
+		return err
+	}
+	return c.setCircuitValue(ctx, CIRCUITMINFLOWTEMPERATURE_URL, systemId, circuit, "minFlowTemperatureSetpoint", temperature)
+}
+
+// Sets the maximum flow temperature setpoint of a heating circuit.
+// If the value is outside of FLOWTEMPERATURE_MIN and FLOWTEMPERATURE_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetCircuitMaxFlowTemperature(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitMaxFlowTemperatureCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitMaxFlowTemperatureCtx is like SetCircuitMaxFlowTemperature, but the http requests are bound to ctx
+func (c *Connection) SetCircuitMaxFlowTemperatureCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	if err := checkRange("maximum flow temperature", temperature, FLOWTEMPERATURE_MIN, FLOWTEMPERATURE_MAX); err != nil {
+		return err
+	}
+	return c.setCircuitValue(ctx, CIRCUITMAXFLOWTEMPERATURE_URL, systemId, circuit, "maxFlowTemperatureSetpoint", temperature)
+}
+
+// Sets the outside temperature above which the heating circuit does not demand heat.
+// If the value is outside of HEATDEMANDLIMIT_MIN and HEATDEMANDLIMIT_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetCircuitHeatDemandLimit(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitHeatDemandLimitCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitHeatDemandLimitCtx is like SetCircuitHeatDemandLimit, but the http requests are bound to ctx
+func (c *Connection) SetCircuitHeatDemandLimitCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	if err := checkRange("heat demand limit", temperature, HEATDEMANDLIMIT_MIN, HEATDEMANDLIMIT_MAX); err != nil {
+		return err
+	}
+	return c.setCircuitValue(ctx, CIRCUITHEATDEMANDLIMIT_URL, systemId, circuit, "heatDemandLimitedByOutsideTemperature", temperature)
+}
+
+func (c *Connection) setCircuitValue(ctx context.Context, urlFormat, systemId string, circuit int, name string, value float64) error {
+	if circuit < 0 {
+		circuit = CIRCUITINDEX_DEFAULT
+	} // if parameter "circuit" is negative, then the default value is used
+
+	url, err := c.systemURL(ctx, systemId, urlFormat, circuit)
+	if err != nil {
+		return err
+	}
+	data := map[string]float64{
+		name: value,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the operation mode of a ventilation (OPERATIONMODE_NORMAL, OPERATIONMODE_REDUCED or OPERATIONMODE_TIME_CONTROLLED)
+func (c *Connection) SetVentilationOperationMode(systemId string, ventilationIndex int, mode OperationMode) error {
+	return c.SetVentilationOperationModeCtx(context.Background(), systemId, ventilationIndex, mode)
+}
+
+// SetVentilationOperationModeCtx is like SetVentilationOperationMode, but the http requests are bound to ctx
+func (c *Connection) SetVentilationOperationModeCtx(ctx context.Context, systemId string, ventilationIndex int, mode OperationMode) error {
+	if ventilationIndex < 0 {
+		ventilationIndex = VENTILATIONINDEX_DEFAULT
+	} // if parameter "ventilationIndex" is negative, then the default value is used
+	if err := checkOperationMode(ventilationOperationModes, mode); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, VENTILATIONOPERATIONMODE_URL, ventilationIndex)
+	if err != nil {
+		return err
+	}
+	data := map[string]OperationMode{
+		"operationMode": mode,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the maximum fan stage of a ventilation during the day.
+// If the fan stage is outside of FANSTAGE_MIN and FANSTAGE_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetVentilationMaxDayFanStage(systemId string, ventilationIndex int, fanStage int) error {
+	return c.SetVentilationMaxDayFanStageCtx(context.Background(), systemId, ventilationIndex, fanStage)
+}
+
+// SetVentilationMaxDayFanStageCtx is like SetVentilationMaxDayFanStage, but the http requests are bound to ctx
+func (c *Connection) SetVentilationMaxDayFanStageCtx(ctx context.Context, systemId string, ventilationIndex int, fanStage int) error {
+	return c.setVentilationFanStage(ctx, systemId, ventilationIndex, FANSTAGE_TYPE_DAY, fanStage)
+}
+
+// Sets the maximum fan stage of a ventilation during the night.
+// If the fan stage is outside of FANSTAGE_MIN and FANSTAGE_MAX, an OutOfRangeError is returned.
+func (c *Connection) SetVentilationMaxNightFanStage(systemId string, ventilationIndex int, fanStage int) error {
+	return c.SetVentilationMaxNightFanStageCtx(context.Background(), systemId, ventilationIndex, fanStage)
+}
+
+// SetVentilationMaxNightFanStageCtx is like SetVentilationMaxNightFanStage, but the http requests are bound to ctx
+func (c *Connection) SetVentilationMaxNightFanStageCtx(ctx context.Context, systemId string, ventilationIndex int, fanStage int) error {
+	return c.setVentilationFanStage(ctx, systemId, ventilationIndex, FANSTAGE_TYPE_NIGHT, fanStage)
+}
+
+func (c *Connection) setVentilationFanStage(ctx context.Context, systemId string, ventilationIndex int, fanStageType string, fanStage int) error {
+	if ventilationIndex < 0 {
+		ventilationIndex = VENTILATIONINDEX_DEFAULT
+	} // if parameter "ventilationIndex" is negative, then the default value is used
+	if err := checkRange("fan stage", float64(fanStage), FANSTAGE_MIN, FANSTAGE_MAX); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, VENTILATIONFANSTAGE_URL, ventilationIndex)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{
+		"maximumFanStage": fanStage,
+		"fanStageType":    fanStageType,
+	}
+	return c.sendJSON(ctx, "PATCH", url, data)
+}
+
+// Sets the time program of a ventilation. As for SetZoneTimeProgram, the limits are only checked if the caller has set timeProgram.MetaInfo.
+func (c *Connection) SetVentilationTimeProgram(systemId string, ventilationIndex int, timeProgram TimeProgram) error {
+	return c.SetVentilationTimeProgramCtx(context.Background(), systemId, ventilationIndex, timeProgram)
+}
+
+// SetVentilationTimeProgramCtx is like SetVentilationTimeProgram, but the http requests are bound to ctx
+func (c *Connection) SetVentilationTimeProgramCtx(ctx context.Context, systemId string, ventilationIndex int, timeProgram TimeProgram) error {
+	if ventilationIndex < 0 {
+		ventilationIndex = VENTILATIONINDEX_DEFAULT
+	} // if parameter "ventilationIndex" is negative, then the default value is used
+	if err := timeProgram.Validate(); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, VENTILATIONTIMEPROGRAM_URL, ventilationIndex)
+	if err != nil {
+		return err
+	}
+	return c.sendJSON(ctx, "PATCH", url, timeProgramData(timeProgram))
+}
+
+// Sets a holiday for all zones and hot water circuits of systemId. During the holiday, the zones are heated to setpoint.
+func (c *Connection) SetHoliday(systemId string, start, end time.Time, setpoint float64) error {
+	return c.SetHolidayCtx(context.Background(), systemId, start, end, setpoint)
+}
+
+// SetHolidayCtx is like SetHoliday, but the http requests are bound to ctx
+func (c *Connection) SetHolidayCtx(ctx context.Context, systemId string, start, end time.Time, setpoint float64) error {
+	state, err := c.GetSystemCtx(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	return c.setHoliday(ctx, systemId, &state, start, end, setpoint)
+}
+
+// Cancels the holiday of all zones and hot water circuits of systemId
+func (c *Connection) CancelHoliday(systemId string) error {
+	return c.CancelHolidayCtx(context.Background(), systemId)
+}
+
+// CancelHolidayCtx is like CancelHoliday, but the http requests are bound to ctx
+func (c *Connection) CancelHolidayCtx(ctx context.Context, systemId string) error {
+	state, err := c.GetSystemCtx(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	return c.cancelHoliday(ctx, systemId, &state)
+}
+
+// Sets a holiday for a zone. During the holiday, the zone is heated to setpoint.
+func (c *Connection) SetZoneHoliday(systemId string, zone int, start, end time.Time, setpoint float64) error {
+	return c.SetZoneHolidayCtx(context.Background(), systemId, zone, start, end, setpoint)
+}
+
+// SetZoneHolidayCtx is like SetZoneHoliday, but the http requests are bound to ctx
+func (c *Connection) SetZoneHolidayCtx(ctx context.Context, systemId string, zone int, start, end time.Time, setpoint float64) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+	if err := checkHoliday(start, end); err != nil {
+		return err
+	}
+	if err := checkRange("holiday setpoint", setpoint, ZONESETPOINT_MIN, ZONESETPOINT_MAX); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, ZONEHOLIDAY_URL, zone)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{
+		"holidayStartDateTime": start.UTC().Format(time.RFC3339),
+		"holidayEndDateTime":   end.UTC().Format(time.RFC3339),
+		"setpoint":             setpoint,
+	}
+	return c.sendJSON(ctx, "POST", url, data)
+}
+
+// Cancels the holiday of a zone
+func (c *Connection) CancelZoneHoliday(systemId string, zone int) error {
+	return c.CancelZoneHolidayCtx(context.Background(), systemId, zone)
+}
+
+// CancelZoneHolidayCtx is like CancelZoneHoliday, but the http requests are bound to ctx
+func (c *Connection) CancelZoneHolidayCtx(ctx context.Context, systemId string, zone int) error {
+	if zone < 0 {
+		zone = ZONEINDEX_DEFAULT
+	} // if parameter "zone" is negative, then the default value is used
+
+	url, err := c.systemURL(ctx, systemId, ZONEHOLIDAY_URL, zone)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+
+	if _, err := doBody(c.client, req); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Sets a holiday for the domestic hot water
+func (c *Connection) SetHotWaterHoliday(systemId string, hotwaterIndex int, start, end time.Time) error {
+	return c.SetHotWaterHolidayCtx(context.Background(), systemId, hotwaterIndex, start, end)
+}
+
+// SetHotWaterHolidayCtx is like SetHotWaterHoliday, but the http requests are bound to ctx
+func (c *Connection) SetHotWaterHolidayCtx(ctx context.Context, systemId string, hotwaterIndex int, start, end time.Time) error {
+	if hotwaterIndex < 0 {
+		hotwaterIndex = HOTWATERINDEX_DEFAULT
+	} // if parameter "hotwaterIndex" is negative, then the default value is used
+	if err := checkHoliday(start, end); err != nil {
+		return err
+	}
+
+	url, err := c.systemURL(ctx, systemId, HOTWATERHOLIDAY_URL, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"holidayStartDateTime": start.UTC().Format(time.RFC3339),
+		"holidayEndDateTime":   end.UTC().Format(time.RFC3339),
+	}
+	return c.sendJSON(ctx, "POST", url, data)
+}
+
+// Cancels the holiday of the domestic hot water
+func (c *Connection) CancelHotWaterHoliday(systemId string, hotwaterIndex int) error {
+	return c.CancelHotWaterHolidayCtx(context.Background(), systemId, hotwaterIndex)
+}
+
+// CancelHotWaterHolidayCtx is like CancelHotWaterHoliday, but the http requests are bound to ctx
+func (c *Connection) CancelHotWaterHolidayCtx(ctx context.Context, systemId string, hotwaterIndex int) error {
+	if hotwaterIndex < 0 {
+		hotwaterIndex = HOTWATERINDEX_DEFAULT
+	} // if parameter "hotwaterIndex" is negative, then the default value is used
+
+	url, err := c.systemURL(ctx, systemId, HOTWATERHOLIDAY_URL, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+
+	if _, err := doBody(c.client, req); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setHoliday sets the holiday for all zones and hot water circuits found in state
+func (c *Connection) setHoliday(ctx context.Context, systemId string, state *SystemStatus, start, end time.Time, setpoint float64) error {
+	for _, zone := range state.Configuration.Zones {
+		if err := c.SetZoneHolidayCtx(ctx, systemId, zone.Index, start, end, setpoint); err != nil {
+			return err
+		}
+	}
+	for _, dhw := range state.Configuration.Dhw {
+		if err := c.SetHotWaterHolidayCtx(ctx, systemId, dhw.Index, start, end); err != nil {
+			return err
+		}
+	}
+	for _, domesticHotWater := range state.Configuration.DomesticHotWater {
+		if err := c.SetHotWaterHolidayCtx(ctx, systemId, domesticHotWater.Index, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelHoliday cancels the holiday for all zones and hot water circuits found in state
+func (c *Connection) cancelHoliday(ctx context.Context, systemId string, state *SystemStatus) error {
+	for _, zone := range state.Configuration.Zones {
+		if err := c.CancelZoneHolidayCtx(ctx, systemId, zone.Index); err != nil {
+			return err
+		}
+	}
+	for _, dhw := range state.Configuration.Dhw {
+		if err := c.CancelHotWaterHolidayCtx(ctx, systemId, dhw.Index); err != nil {
+			return err
+		}
+	}
+	for _, domesticHotWater := range state.Configuration.DomesticHotWater {
+		if err := c.CancelHotWaterHolidayCtx(ctx, systemId, domesticHotWater.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkHoliday(start, end time.Time) error {
+	if !end.After(start) {
+		return fmt.Errorf("holiday end %s is not after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Operation modes of zones, cooling and domestic hot water per control identifier
+var (
+	zoneOperationModes = map[string][]OperationMode{
+		CONTROL_IDENTIFIER_TLI:    {OPERATIONMODE_OFF, OPERATIONMODE_MANUAL, OPERATIONMODE_TIME_CONTROLLED},
+		CONTROL_IDENTIFIER_VRC700: {OPERATIONMODE_OFF, OPERATIONMODE_DAY, OPERATIONMODE_AUTO, OPERATIONMODE_SET_BACK},
+	}
+	coolingOperationModes = map[string][]OperationMode{
+		CONTROL_IDENTIFIER_TLI:    {OPERATIONMODE_OFF, OPERATIONMODE_MANUAL, OPERATIONMODE_TIME_CONTROLLED},
+		CONTROL_IDENTIFIER_VRC700: {OPERATIONMODE_OFF, OPERATIONMODE_DAY, OPERATIONMODE_AUTO},
+	}
+	hotWaterOperationModes = map[string][]OperationMode{
+		CONTROL_IDENTIFIER_TLI:    {OPERATIONMODE_OFF, OPERATIONMODE_MANUAL, OPERATIONMODE_TIME_CONTROLLED},
+		CONTROL_IDENTIFIER_VRC700: {OPERATIONMODE_OFF, OPERATIONMODE_DAY, OPERATIONMODE_AUTO},
+	}
+)
+
+// Operation modes of ventilations, which do not depend on the control identifier
+var ventilationOperationModes = []OperationMode{OPERATIONMODE_NORMAL, OPERATIONMODE_REDUCED, OPERATIONMODE_TIME_CONTROLLED}
+
+// operationModes returns the operation modes of the control identifier.
+// For an unknown control identifier, the operation modes of CONTROL_IDENTIFIER_TLI are returned.
+func (c *Connection) operationModes(modes map[string][]OperationMode, controlIdentifier string) []OperationMode {
+	if res, ok := modes[controlIdentifier]; ok {
+		return res
+	}
+	c.debug("Unknown control identifier %s. Using the operation modes of %s", controlIdentifier, CONTROL_IDENTIFIER_TLI)
+	return modes[CONTROL_IDENTIFIER_TLI]
+}
+
+func (c *Connection) debug(fmt string, arg ...any) {
+	if c.logger != nil {
+		c.logger.Printf(fmt, arg...)
+	}
+}
+
+func checkOperationMode(modes []OperationMode, mode OperationMode) error {
+	if slices.Contains(modes, mode) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidOperationMode, mode)
+}
+
+// isNotFound returns true if err is an APIError with http status 404
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// sendJSON sends data as json body to url using the given http method
+func (c *Connection) sendJSON(ctx context.Context, method, url string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+
+	if _, err := doBody(c.client, req); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,111 +1,198 @@
 package sensonet
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
+
+	"github.com/benbjohnson/clock"
 )
 
 type Controller struct {
 	conn               *Connection
 	logger             Logger
+	cacheGroup         *CacheGroup
 	homesCache         Cacheable[Homes]
-	systemsCache       Cacheable[AllSystems]
-	systemDevicesCache Cacheable[AllSystemDevices]
-	systemMpcDataCache Cacheable[AllSystemMpcData]
+	systemsCache       *keyedCached[SystemStatus]
+	systemDevicesCache *keyedCached[SystemDevices]
+	systemMpcDataCache *keyedCached[[]MpcDevice]
+	roomsCache         *keyedCached[[]Room]
+	diagnosticsCache   *keyedCached[SystemDiagnostics]
+	liveReportCache    *keyedCached[LiveReport]
+	quickModeState     atomic.Pointer[SystemStatus] // latest state of Homes[0].SystemId, not yet used for the quick mode
 	currentQuickmode   string
 	quickmodeStarted   time.Time
 	quickmodeStopped   time.Time
 	quickModeExpiresAt string
+	durations          ctrlDurations
+	cacheOpts          []CacheOption
+	clock              clock.Clock
+}
+
+// ctrlDurations are the cache durations and the time windows of the quick mode handling of a controller
+type ctrlDurations struct {
+	homes               time.Duration
+	systems             time.Duration
+	devices             time.Duration
+	mpcData             time.Duration
+	rooms               time.Duration
+	diagnostics         time.Duration
+	liveReport          time.Duration
+	quickModeHysteresis time.Duration // minimum time between a start or stop and a change of the quick mode detected in the system state
+	idleMode            time.Duration // duration of the idle mode, if no quick mode was possible
 }
 
 const CACHE_DURATION_HOMES = 1800
 const CACHE_DURATION_SYSTEMS = 90
 const CACHE_DURATION_DEVICES = 1800
 const CACHE_DURATION_MPCDATA = 90
+const CACHE_DURATION_ROOMS = 90
+const CACHE_DURATION_DIAGNOSTICS = 300
+const CACHE_DURATION_LIVEREPORT = 60
+
+// Minimum cache duration in seconds, to protect the quota of the Vaillant API
+const CACHE_DURATION_MIN = 30
+
+// Default duration in seconds for which the idle mode (QUICKMODE_NOTHING) is kept, if no quick mode was possible
+const IDLEMODE_DURATION = 10 * 60
+
+// ErrDurationTooShort is returned (wrapped) by NewController, if a duration option is below its minimum
+var ErrDurationTooShort = errors.New("duration too short")
 
 // NewController creates a new Sensonet controller.
 func NewController(conn *Connection, opts ...CtrlOption) (*Controller, error) {
 	ctrl := &Controller{
 		conn:               conn,
-		quickmodeStarted:   time.Now(),
-		quickmodeStopped:   time.Now().Add(-2 * time.Minute), // time stamp is set in the past so that first call of refreshCurrentQuickMode() changes currentQuickmode if necessary
+		cacheGroup:         NewCacheGroup(),
+		clock:              clock.New(),
 		quickModeExpiresAt: "",
+		durations: ctrlDurations{
+			homes:       CACHE_DURATION_HOMES * time.Second,
+			systems:     CACHE_DURATION_SYSTEMS * time.Second,
+			devices:     CACHE_DURATION_DEVICES * time.Second,
+			mpcData:     CACHE_DURATION_MPCDATA * time.Second,
+			rooms:       CACHE_DURATION_ROOMS * time.Second,
+			diagnostics: CACHE_DURATION_DIAGNOSTICS * time.Second,
+			liveReport:  CACHE_DURATION_LIVEREPORT * time.Second,
+			idleMode:    IDLEMODE_DURATION * time.Second,
+		},
 	}
 
 	for _, opt := range opts {
 		opt(ctrl)
 	}
 
-	ctrl.homesCache = ResettableCached(func() (Homes, error) {
+	if ctrl.durations.quickModeHysteresis == 0 {
+		ctrl.durations.quickModeHysteresis = 2 * ctrl.durations.systems
+	}
+	if err := ctrl.durations.validate(); err != nil {
+		ctrl.cacheGroup.Close()
+		return nil, err
+	}
+	ctrl.quickmodeStarted = ctrl.clock.Now()
+	// time stamp is set in the past so that first call of refreshCurrentQuickMode() changes currentQuickmode if necessary
+	ctrl.quickmodeStopped = ctrl.clock.Now().Add(-ctrl.durations.quickModeHysteresis)
+
+	cacheOpts := append([]CacheOption{WithCacheGroup(ctrl.cacheGroup), WithCacheClock(ctrl.clock)}, ctrl.cacheOpts...)
+
+	ctrl.homesCache = ResettableCachedCtx(func(ctx context.Context) (Homes, error) {
 		//var res Homes
-		res, err := ctrl.conn.GetHomes()
+		res, err := ctrl.conn.GetHomesCtx(ctx)
 		return res, err
-	}, CACHE_DURATION_HOMES*time.Second)
-
-	ctrl.systemsCache = ResettableCached(func() (AllSystems, error) {
-		var res AllSystems
-		homes, err := ctrl.homesCache.Get()
-		for i, home := range homes {
-			var systemAndStatus SystemAndStatus
-			systemAndStatus.SystemId = home.SystemID
-			systemAndStatus.SystemStatus, err = ctrl.conn.GetSystem(home.SystemID)
-			if err != nil {
-				return res, err
-			}
-			if len(res.SystemsAndStatus) <= i {
-				res.SystemsAndStatus = append(res.SystemsAndStatus, systemAndStatus)
-			} else {
-				res.SystemsAndStatus[i] = systemAndStatus
-			}
-			// For the beginning, currentQuickMode is only calculated from the system status of Homes[0].SystemId
-			if i == 0 {
-				ctrl.refreshCurrentQuickMode(&systemAndStatus.SystemStatus)
-			}
+	}, ctrl.durations.homes, cacheOpts...)
+
+	ctrl.systemsCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) (SystemStatus, error) {
+		state, err := ctrl.conn.GetSystemCtx(ctx, systemId)
+		if err != nil {
+			return state, err
 		}
-		return res, err
-	}, CACHE_DURATION_SYSTEMS*time.Second)
-
-	ctrl.systemDevicesCache = ResettableCached(func() (AllSystemDevices, error) {
-		var res AllSystemDevices
-		homes, err := ctrl.homesCache.Get()
-		for i, home := range homes {
-			var systemDevicesAndSystemId SystemDevicesAndSystemId
-			systemDevicesAndSystemId.SystemId = home.SystemID
-			systemDevicesAndSystemId.SystemDevices, err = ctrl.conn.GetSystemDevices(home.SystemID)
-			if err != nil {
-				return res, err
-			}
-			if len(res.SystemDevicesAndSystemId) <= i {
-				res.SystemDevicesAndSystemId = append(res.SystemDevicesAndSystemId, systemDevicesAndSystemId)
-			} else {
-				res.SystemDevicesAndSystemId[i] = systemDevicesAndSystemId
-			}
+		// For the beginning, currentQuickMode is only calculated from the system status of Homes[0].SystemId.
+		// The update may run in the background, so the quick mode is refreshed by the next caller of GetSystemCtx().
+		if homes, err := ctrl.homesCache.GetCtx(ctx); ignoreStale(err) == nil && len(homes) > 0 && homes[0].SystemID == systemId {
+			ctrl.quickModeState.Store(&state)
 		}
-		return res, err
-	}, CACHE_DURATION_DEVICES*time.Second)
-
-	ctrl.systemMpcDataCache = ResettableCached(func() (AllSystemMpcData, error) {
-		var res AllSystemMpcData
-		homes, err := ctrl.homesCache.Get()
-		for i, home := range homes {
-			var systemMpcData SystemMpcData
-			systemMpcData.SystemId = home.SystemID
-			systemMpcData.MpcData.Devices, err = ctrl.conn.GetMpcData(home.SystemID)
-			if err != nil {
-				return res, err
-			}
-			if len(res.SystemMpcData) <= i {
-				res.SystemMpcData = append(res.SystemMpcData, systemMpcData)
-			} else {
-				res.SystemMpcData[i] = systemMpcData
+		return state, nil
+	}, ctrl.checkSystemId, ctrl.durations.systems, cacheOpts...)
+
+	ctrl.systemDevicesCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) (SystemDevices, error) {
+		systemDevices, err := ctrl.conn.GetSystemDevicesCtx(ctx, systemId)
+		if err != nil {
+			return systemDevices, err
+		}
+		// firmware and online state of the gateway are taken from the cached homes, if they are missing
+		if gateway := &systemDevices.Gateway; gateway.FirmwareVersion == "" || gateway.OnlineState == "" {
+			if homes, err := ctrl.homesCache.GetCtx(ctx); ignoreStale(err) == nil {
+				completeGateway(gateway, homes, systemId)
 			}
 		}
+		return systemDevices, nil
+	}, ctrl.checkSystemId, ctrl.durations.devices, cacheOpts...)
+
+	ctrl.systemMpcDataCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) ([]MpcDevice, error) {
+		return ctrl.conn.GetMpcDataCtx(ctx, systemId)
+	}, ctrl.checkSystemId, ctrl.durations.mpcData, cacheOpts...)
+
+	ctrl.roomsCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) ([]Room, error) {
+		return ctrl.conn.GetRoomsCtx(ctx, systemId)
+	}, ctrl.checkSystemId, ctrl.durations.rooms, cacheOpts...)
+
+	ctrl.diagnosticsCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) (SystemDiagnostics, error) {
+		var res SystemDiagnostics
+		var err error
+		if res.Diagnostics, err = ctrl.conn.GetDiagnosticsCtx(ctx, systemId); err != nil {
+			return res, err
+		}
+		res.Notifications, err = ctrl.conn.GetNotificationsCtx(ctx, systemId)
 		return res, err
-	}, CACHE_DURATION_MPCDATA*time.Second)
+	}, ctrl.checkSystemId, ctrl.durations.diagnostics, cacheOpts...)
+
+	ctrl.liveReportCache = resettableKeyedCachedCtx(func(ctx context.Context, systemId string) (LiveReport, error) {
+		return ctrl.conn.GetLiveReportCtx(ctx, systemId)
+	}, ctrl.checkSystemId, ctrl.durations.liveReport, cacheOpts...)
 
 	return ctrl, nil
 }
 
+// ResetCache resets all caches of the controller, so that the next calls request fresh data
+func (c *Controller) ResetCache() {
+	c.cacheGroup.Reset()
+}
+
+// Close releases the caches of the controller. The controller must not be used afterwards.
+func (c *Controller) Close() {
+	c.cacheGroup.Close()
+}
+
+// validate checks the durations against their minimums. The quick mode hysteresis must not be shorter than the
+// systems cache duration, as a change of the quick mode can not be detected earlier.
+func (d ctrlDurations) validate() error {
+	for _, cache := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"homes", d.homes},
+		{"systems", d.systems},
+		{"devices", d.devices},
+		{"mpc data", d.mpcData},
+		{"rooms", d.rooms},
+		{"diagnostics", d.diagnostics},
+		{"live report", d.liveReport},
+	} {
+		if cache.duration < CACHE_DURATION_MIN*time.Second {
+			return fmt.Errorf("%w: %s cache duration %v, minimum is %v", ErrDurationTooShort, cache.name, cache.duration, CACHE_DURATION_MIN*time.Second)
+		}
+	}
+	if d.quickModeHysteresis < d.systems {
+		return fmt.Errorf("%w: quick mode hysteresis %v, minimum is the systems cache duration %v", ErrDurationTooShort, d.quickModeHysteresis, d.systems)
+	}
+	if d.idleMode <= 0 {
+		return fmt.Errorf("%w: idle mode duration %v", ErrDurationTooShort, d.idleMode)
+	}
+	return nil
+}
+
 func (c *Controller) debug(fmt string, arg ...any) {
 	if c.logger != nil {
 		c.logger.Printf(fmt, arg...)
@@ -114,121 +201,273 @@ func (c *Controller) debug(fmt string, arg ...any) {
 
 // Returns all "homes" that belong to the current user under the myVaillant portal
 func (c *Controller) GetHomes() (Homes, error) {
-	homes, err := c.homesCache.Get()
-	if err != nil {
+	return c.GetHomesCtx(context.Background())
+}
+
+// GetHomesCtx is like GetHomes, but the http requests are bound to ctx
+func (c *Controller) GetHomesCtx(ctx context.Context) (Homes, error) {
+	homes, err := c.homesCache.GetCtx(ctx)
+	if ignoreStale(err) != nil {
 		return nil, err
 	}
 	if len(homes) < 1 {
 		return nil, fmt.Errorf("error: no homes")
 	}
-	return homes, nil
+	return homes, err
 }
 
-// Returns the system report (state, properties and configuration) for a specific systemId
+// Returns the system report (state, properties and configuration) for a specific systemId.
+// With WithServeStaleData(), the last known state is returned together with a StaleError, if the update failed.
 func (c *Controller) GetSystem(systemId string) (SystemStatus, error) {
-	var systemStatus SystemStatus
-	systems, err := c.systemsCache.Get()
-	if err != nil {
-		return systemStatus, err
+	return c.GetSystemCtx(context.Background(), systemId)
+}
+
+// GetSystemCtx is like GetSystem, but the http requests are bound to ctx
+func (c *Controller) GetSystemCtx(ctx context.Context, systemId string) (SystemStatus, error) {
+	state, err := c.systemsCache.GetCtx(ctx, systemId)
+	if quickModeState := c.quickModeState.Swap(nil); quickModeState != nil {
+		c.refreshCurrentQuickMode(quickModeState)
 	}
-	for _, sys := range systems.SystemsAndStatus {
-		if sys.SystemId == systemId {
-			return sys.SystemStatus, nil
+	return state, err
+}
+
+// getSystem is like GetSystemCtx, but a stale system state is used without error.
+// It is used by the setters, which only need the configuration of the system.
+func (c *Controller) getSystem(ctx context.Context, systemId string) (SystemStatus, error) {
+	state, err := c.GetSystemCtx(ctx, systemId)
+	return state, ignoreStale(err)
+}
+
+// ignoreStale returns nil for a StaleError, so that the stale value is used like a current one
+func ignoreStale(err error) error {
+	var staleErr *StaleError
+	if errors.As(err, &staleErr) {
+		return nil
+	}
+	return err
+}
+
+// checkSystemId returns an error if systemId is not the system of one of the homes
+func (c *Controller) checkSystemId(ctx context.Context, systemId string) error {
+	homes, err := c.homesCache.GetCtx(ctx)
+	if err := ignoreStale(err); err != nil {
+		return err
+	}
+	for _, home := range homes {
+		if home.SystemID == systemId {
+			return nil
 		}
 	}
-	return systemStatus, fmt.Errorf("no data found for system %s", systemId)
+	return fmt.Errorf("no data found for system %s", systemId)
 }
 
 // Returns the device data for given criteria
 func (c *Controller) GetDeviceData(systemId string, whichDevices int) ([]DeviceAndInfo, error) {
+	return c.GetDeviceDataCtx(context.Background(), systemId, whichDevices)
+}
+
+// GetDeviceDataCtx is like GetDeviceData, but the http requests are bound to ctx
+func (c *Controller) GetDeviceDataCtx(ctx context.Context, systemId string, whichDevices int) ([]DeviceAndInfo, error) {
 	var devices []DeviceAndInfo
-	systemDevices, err := c.GetSystemDevices(systemId)
-	if err != nil {
+	systemDevices, err := c.GetSystemDevicesCtx(ctx, systemId)
+	if ignoreStale(err) != nil {
 		return devices, err
 	}
-	var deviceAndInfo DeviceAndInfo
-	if systemDevices.PrimaryHeatGenerator.DeviceUUID != "" && (whichDevices == DEVICES_PRIMARY_HEATER || whichDevices == DEVICES_ALL) {
-		deviceAndInfo.Device = systemDevices.PrimaryHeatGenerator
-		deviceAndInfo.Info = "primary_heat_generator"
-		devices = append(devices, deviceAndInfo)
-	}
-	if whichDevices == DEVICES_SECONDARY_HEATER || whichDevices == DEVICES_ALL {
-		for _, secHeatGen := range systemDevices.SecondaryHeatGenerators {
-			deviceAndInfo.Device = secHeatGen
-			deviceAndInfo.Info = "secondary_heat_generator"
-			devices = append(devices, deviceAndInfo)
-		}
-	}
-
-	if systemDevices.ElectricBackupHeater.DeviceUUID != "" && (whichDevices == DEVICES_BACKUP_HEATER || whichDevices == DEVICES_ALL) {
-		deviceAndInfo.Device = systemDevices.ElectricBackupHeater
-		deviceAndInfo.Info = "electric_backup_heater"
-		devices = append(devices, deviceAndInfo)
-	}
-	return devices, nil
+	return GetDevicesAndInfo(systemDevices, whichDevices), err
 }
 
 // Returns the energy data for systemId, deviceUuid and other given criteria
 func (c *Controller) GetEnergyData(systemId, deviceUuid, operationMode, energyType, resolution string, startDate, endDate time.Time) (EnergyData, error) {
-	return c.conn.GetEnergyData(systemId, deviceUuid, operationMode, energyType, resolution, startDate, endDate)
+	return c.GetEnergyDataCtx(context.Background(), systemId, deviceUuid, operationMode, energyType, resolution, startDate, endDate)
+}
+
+// GetEnergyDataCtx is like GetEnergyData, but the http requests are bound to ctx
+func (c *Controller) GetEnergyDataCtx(ctx context.Context, systemId, deviceUuid, operationMode, energyType, resolution string, startDate, endDate time.Time) (EnergyData, error) {
+	return c.conn.GetEnergyDataCtx(ctx, systemId, deviceUuid, operationMode, energyType, resolution, startDate, endDate)
 }
 
 // Returns the mpc data for systemId
 func (c *Controller) GetMpcData(systemId string) ([]MpcDevice, error) {
-	var mpcData MpcData
-	allSystemMpcData, err := c.systemMpcDataCache.Get()
-	if err != nil {
-		return mpcData.Devices, err
+	return c.GetMpcDataCtx(context.Background(), systemId)
+}
+
+// GetMpcDataCtx is like GetMpcData, but the http requests are bound to ctx
+func (c *Controller) GetMpcDataCtx(ctx context.Context, systemId string) ([]MpcDevice, error) {
+	return c.systemMpcDataCache.GetCtx(ctx, systemId)
+}
+
+// Returns the live report (current sensor values like tank, flow and return temperatures) for systemId
+func (c *Controller) GetLiveReport(systemId string) (LiveReport, error) {
+	return c.GetLiveReportCtx(context.Background(), systemId)
+}
+
+// GetLiveReportCtx is like GetLiveReport, but the http requests are bound to ctx
+func (c *Controller) GetLiveReportCtx(ctx context.Context, systemId string) (LiveReport, error) {
+	return c.liveReportCache.GetCtx(ctx, systemId)
+}
+
+// Returns the active status, error and maintenance codes of all devices of systemId
+func (c *Controller) GetDiagnostics(systemId string) ([]Diagnostic, error) {
+	return c.GetDiagnosticsCtx(context.Background(), systemId)
+}
+
+// GetDiagnosticsCtx is like GetDiagnostics, but the http requests are bound to ctx
+func (c *Controller) GetDiagnosticsCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	systemDiagnostics, err := c.diagnosticsCache.GetCtx(ctx, systemId)
+	return systemDiagnostics.Diagnostics, err
+}
+
+// Returns the active maintenance codes of all devices of systemId
+func (c *Controller) GetMaintenance(systemId string) ([]Diagnostic, error) {
+	return c.GetMaintenanceCtx(context.Background(), systemId)
+}
+
+// GetMaintenanceCtx is like GetMaintenance, but the http requests are bound to ctx
+func (c *Controller) GetMaintenanceCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	systemDiagnostics, err := c.diagnosticsCache.GetCtx(ctx, systemId)
+	return FilterDiagnostics(systemDiagnostics.Diagnostics, DIAGNOSTIC_TYPE_MAINTENANCE), err
+}
+
+// Returns the notification history of systemId
+func (c *Controller) GetNotifications(systemId string) ([]Diagnostic, error) {
+	return c.GetNotificationsCtx(context.Background(), systemId)
+}
+
+// GetNotificationsCtx is like GetNotifications, but the http requests are bound to ctx
+func (c *Controller) GetNotificationsCtx(ctx context.Context, systemId string) ([]Diagnostic, error) {
+	systemDiagnostics, err := c.diagnosticsCache.GetCtx(ctx, systemId)
+	return systemDiagnostics.Notifications, err
+}
+
+// Returns the ambisense rooms of systemId. Systems without ambisense radiator thermostats have no rooms.
+func (c *Controller) GetRooms(systemId string) ([]Room, error) {
+	return c.GetRoomsCtx(context.Background(), systemId)
+}
+
+// GetRoomsCtx is like GetRooms, but the http requests are bound to ctx
+func (c *Controller) GetRoomsCtx(ctx context.Context, systemId string) ([]Room, error) {
+	return c.roomsCache.GetCtx(ctx, systemId)
+}
+
+// Returns the ambisense room with the given index (temperature, humidity, setpoint, time program, ...)
+func (c *Controller) GetRoom(systemId string, room int) (Room, error) {
+	return c.GetRoomCtx(context.Background(), systemId, room)
+}
+
+// GetRoomCtx is like GetRoom, but the http requests are bound to ctx
+func (c *Controller) GetRoomCtx(ctx context.Context, systemId string, room int) (Room, error) {
+	rooms, err := c.GetRoomsCtx(ctx, systemId)
+	if ignoreStale(err) != nil {
+		return Room{}, err
 	}
-	for _, systemMpcData := range allSystemMpcData.SystemMpcData {
-		if systemMpcData.SystemId == systemId {
-			return systemMpcData.MpcData.Devices, err
-		}
+	roomData := GetRoomData(rooms, room)
+	if roomData == nil {
+		return Room{}, fmt.Errorf("no room %d found for system %s", room, systemId)
 	}
-	return mpcData.Devices, fmt.Errorf("no mpc data found for system %s", systemId)
+	return *roomData, err
 }
 
-// Returns the system devices for a specific systemId
-func (c *Controller) GetSystemDevices(systemId string) (SystemDevices, error) {
-	var systemDevices SystemDevices
-	allSystemDevices, err := c.systemDevicesCache.Get()
-	if err != nil {
-		return systemDevices, err
+// Sets the time program of an ambisense room
+func (c *Controller) SetRoomTimeProgram(systemId string, room int, timeProgram RoomTimeProgram) error {
+	return c.SetRoomTimeProgramCtx(context.Background(), systemId, room, timeProgram)
+}
+
+// SetRoomTimeProgramCtx is like SetRoomTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetRoomTimeProgramCtx(ctx context.Context, systemId string, room int, timeProgram RoomTimeProgram) error {
+	err := c.conn.SetRoomTimeProgramCtx(ctx, systemId, room, timeProgram)
+	if err == nil {
+		c.roomsCache.ResetKey(systemId)
 	}
-	for _, sys := range allSystemDevices.SystemDevicesAndSystemId {
-		if sys.SystemId == systemId {
-			return sys.SystemDevices, nil
-		}
+	return err
+}
+
+// Starts a quick veto for an ambisense room. Setpoint is the temperature in °C, duration is given in hours.
+func (c *Controller) StartRoomQuickVeto(systemId string, room int, setpoint float32, duration float32) error {
+	return c.StartRoomQuickVetoCtx(context.Background(), systemId, room, setpoint, duration)
+}
+
+// StartRoomQuickVetoCtx is like StartRoomQuickVeto, but the http requests are bound to ctx
+func (c *Controller) StartRoomQuickVetoCtx(ctx context.Context, systemId string, room int, setpoint float32, duration float32) error {
+	err := c.conn.StartRoomQuickVetoCtx(ctx, systemId, room, setpoint, duration)
+	if err == nil {
+		c.roomsCache.ResetKey(systemId)
 	}
-	return systemDevices, fmt.Errorf("no data found for system %s", systemId)
+	return err
+}
+
+// Stops the quick veto of an ambisense room
+func (c *Controller) StopRoomQuickVeto(systemId string, room int) error {
+	return c.StopRoomQuickVetoCtx(context.Background(), systemId, room)
+}
+
+// StopRoomQuickVetoCtx is like StopRoomQuickVeto, but the http requests are bound to ctx
+func (c *Controller) StopRoomQuickVetoCtx(ctx context.Context, systemId string, room int) error {
+	err := c.conn.StopRoomQuickVetoCtx(ctx, systemId, room)
+	if err == nil {
+		c.roomsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Returns the control identifier of a specific systemId (CONTROL_IDENTIFIER_TLI or CONTROL_IDENTIFIER_VRC700)
+func (c *Controller) GetControlIdentifier(systemId string) (string, error) {
+	return c.GetControlIdentifierCtx(context.Background(), systemId)
+}
+
+// GetControlIdentifierCtx is like GetControlIdentifier, but the http requests are bound to ctx
+func (c *Controller) GetControlIdentifierCtx(ctx context.Context, systemId string) (string, error) {
+	return c.conn.GetControlIdentifierCtx(ctx, systemId)
+}
+
+// Returns the system devices for a specific systemId
+func (c *Controller) GetSystemDevices(systemId string) (SystemDevices, error) {
+	return c.GetSystemDevicesCtx(context.Background(), systemId)
+}
+
+// GetSystemDevicesCtx is like GetSystemDevices, but the http requests are bound to ctx
+func (c *Controller) GetSystemDevicesCtx(ctx context.Context, systemId string) (SystemDevices, error) {
+	return c.systemDevicesCache.GetCtx(ctx, systemId)
 }
 
 // Returns the current power consumption for systemId
 func (c *Controller) GetSystemCurrentPower(systemId string) (float64, error) {
-	mpcDevices, err := c.GetMpcData(systemId)
-	if err != nil || len(mpcDevices) < 1 {
+	return c.GetSystemCurrentPowerCtx(context.Background(), systemId)
+}
+
+// GetSystemCurrentPowerCtx is like GetSystemCurrentPower, but the http requests are bound to ctx
+func (c *Controller) GetSystemCurrentPowerCtx(ctx context.Context, systemId string) (float64, error) {
+	mpcDevices, err := c.GetMpcDataCtx(ctx, systemId)
+	if ignoreStale(err) != nil || len(mpcDevices) < 1 {
 		return -1.0, err
 	}
 	totalPower := 0.0
 	for _, dev := range mpcDevices {
 		totalPower = totalPower + dev.CurrentPower
 	}
-	return totalPower, nil
+	return totalPower, err
 }
 
 // Returns the current power consumption and product name for deviceUuid. If "All" is given as deviceUuid, then the function return the power consumption and product name for all devices of systemId
 func (c *Controller) GetDeviceCurrentPower(systemId, deviceUuid string) (DevicePowerMap, error) {
+	return c.GetDeviceCurrentPowerCtx(context.Background(), systemId, deviceUuid)
+}
+
+// GetDeviceCurrentPowerCtx is like GetDeviceCurrentPower, but the http requests are bound to ctx
+func (c *Controller) GetDeviceCurrentPowerCtx(ctx context.Context, systemId, deviceUuid string) (DevicePowerMap, error) {
 	devicePowerMap := make(DevicePowerMap)
 	if deviceUuid == "All" {
 		devicePowerMap["All"] = DevicePower{CurrentPower: -1.0, ProductName: "All Devices"}
 	}
-	mpcDevices, err := c.GetMpcData(systemId)
-	if err != nil || len(mpcDevices) < 1 {
+	mpcDevices, err := c.GetMpcDataCtx(ctx, systemId)
+	if ignoreStale(err) != nil || len(mpcDevices) < 1 {
 		return devicePowerMap, err
 	}
-	devices, err := c.GetDeviceData(systemId, DEVICES_ALL)
-	if err != nil {
-		return devicePowerMap, err
+	devices, devicesErr := c.GetDeviceDataCtx(ctx, systemId, DEVICES_ALL)
+	if ignoreStale(devicesErr) != nil {
+		return devicePowerMap, devicesErr
+	}
+	if err == nil {
+		err = devicesErr
 	}
 	totalPower := 0.0
 	for _, dev := range mpcDevices {
@@ -242,7 +481,7 @@ func (c *Controller) GetDeviceCurrentPower(systemId, deviceUuid string) (DeviceP
 		}
 	}
 	devicePowerMap["All"] = DevicePower{CurrentPower: totalPower, ProductName: "All Devices"}
-	return devicePowerMap, nil
+	return devicePowerMap, err
 }
 
 func (c *Controller) GetCurrentQuickMode() string {
@@ -274,64 +513,89 @@ func (c *Controller) refreshCurrentQuickMode(state *SystemStatus) {
 		}
 	}
 	if newQuickMode != c.currentQuickmode {
-		if newQuickMode == "" && time.Now().After(c.quickmodeStarted.Add(2*CACHE_DURATION_SYSTEMS*time.Second)) {
-			if c.currentQuickmode == QUICKMODE_NOTHING && time.Now().Before(c.quickmodeStarted.Add(10*time.Minute)) {
-				c.debug("Idle mode active for less then 10 minutes. Keeping the idle mode")
+		if newQuickMode == "" && c.clock.Now().After(c.quickmodeStarted.Add(c.durations.quickModeHysteresis)) {
+			if c.currentQuickmode == QUICKMODE_NOTHING && c.clock.Now().Before(c.quickmodeStarted.Add(c.durations.idleMode)) {
+				c.debug("Idle mode active for less then %v. Keeping the idle mode", c.durations.idleMode)
 			} else {
 				c.debug(fmt.Sprintf("Old quickmode: \"%s\"   New quickmode: \"%s\"", c.currentQuickmode, newQuickMode))
 				c.currentQuickmode = newQuickMode
-				c.quickmodeStopped = time.Now()
+				c.quickmodeStopped = c.clock.Now()
 			}
 		}
-		if newQuickMode != "" && time.Now().After(c.quickmodeStopped.Add(2*CACHE_DURATION_SYSTEMS*time.Second)) {
+		if newQuickMode != "" && c.clock.Now().After(c.quickmodeStopped.Add(c.durations.quickModeHysteresis)) {
 			c.debug(fmt.Sprintf("Old quickmode: \"%s\"   New quickmode: \"%s\"", c.currentQuickmode, newQuickMode))
 			c.currentQuickmode = newQuickMode
-			c.quickmodeStarted = time.Now()
+			c.quickmodeStarted = c.clock.Now()
 		}
 	}
 }
 
 func (c *Controller) StartZoneQuickVeto(systemId string, zone int, setpoint float32, duration float32) error {
-	err := c.conn.StartZoneQuickVeto(systemId, zone, setpoint, duration)
+	return c.StartZoneQuickVetoCtx(context.Background(), systemId, zone, setpoint, duration)
+}
+
+// StartZoneQuickVetoCtx is like StartZoneQuickVeto, but the http requests are bound to ctx
+func (c *Controller) StartZoneQuickVetoCtx(ctx context.Context, systemId string, zone int, setpoint float32, duration float32) error {
+	err := c.conn.StartZoneQuickVetoCtx(ctx, systemId, zone, setpoint, duration)
 	if err == nil && c.currentQuickmode != QUICKMODE_HOTWATER {
 		c.currentQuickmode = QUICKMODE_HEATING
-		c.quickmodeStarted = time.Now()
+		c.quickmodeStarted = c.clock.Now()
 	}
 	return err
 }
 
 func (c *Controller) StopZoneQuickVeto(systemId string, zone int) error {
-	err := c.conn.StopZoneQuickVeto(systemId, zone)
+	return c.StopZoneQuickVetoCtx(context.Background(), systemId, zone)
+}
+
+// StopZoneQuickVetoCtx is like StopZoneQuickVeto, but the http requests are bound to ctx
+func (c *Controller) StopZoneQuickVetoCtx(ctx context.Context, systemId string, zone int) error {
+	err := c.conn.StopZoneQuickVetoCtx(ctx, systemId, zone)
 	if err == nil && c.currentQuickmode != QUICKMODE_HOTWATER {
 		c.currentQuickmode = ""
-		c.quickmodeStopped = time.Now()
-		c.systemsCache.Reset()
+		c.quickmodeStopped = c.clock.Now()
+		c.systemsCache.ResetKey(systemId)
 	}
 	return err
 }
 
 func (c *Controller) StartHotWaterBoost(systemId string, hotwaterIndex int) error {
-	err := c.conn.StartHotWaterBoost(systemId, hotwaterIndex)
+	return c.StartHotWaterBoostCtx(context.Background(), systemId, hotwaterIndex)
+}
+
+// StartHotWaterBoostCtx is like StartHotWaterBoost, but the http requests are bound to ctx
+func (c *Controller) StartHotWaterBoostCtx(ctx context.Context, systemId string, hotwaterIndex int) error {
+	err := c.conn.StartHotWaterBoostCtx(ctx, systemId, hotwaterIndex)
 	if err == nil {
 		c.currentQuickmode = QUICKMODE_HOTWATER
-		c.quickmodeStarted = time.Now()
+		c.quickmodeStarted = c.clock.Now()
 	}
 	return err
 }
 
 func (c *Controller) StopHotWaterBoost(systemId string, hotwaterIndex int) error {
-	err := c.conn.StopHotWaterBoost(systemId, hotwaterIndex)
+	return c.StopHotWaterBoostCtx(context.Background(), systemId, hotwaterIndex)
+}
+
+// StopHotWaterBoostCtx is like StopHotWaterBoost, but the http requests are bound to ctx
+func (c *Controller) StopHotWaterBoostCtx(ctx context.Context, systemId string, hotwaterIndex int) error {
+	err := c.conn.StopHotWaterBoostCtx(ctx, systemId, hotwaterIndex)
 	if err == nil && c.currentQuickmode != QUICKMODE_HEATING {
 		c.currentQuickmode = ""
-		c.quickmodeStopped = time.Now()
-		c.systemsCache.Reset()
+		c.quickmodeStopped = c.clock.Now()
+		c.systemsCache.ResetKey(systemId)
 	}
 	return err
 }
 
 func (c *Controller) StartStrategybased(systemId string, strategy int, heatingPar *HeatingParStruct, hotwaterPar *HotwaterParStruct) (string, error) {
-	c.systemsCache.Reset()
-	state, err := c.GetSystem(systemId)
+	return c.StartStrategybasedCtx(context.Background(), systemId, strategy, heatingPar, hotwaterPar)
+}
+
+// StartStrategybasedCtx is like StartStrategybased, but the http requests are bound to ctx
+func (c *Controller) StartStrategybasedCtx(ctx context.Context, systemId string, strategy int, heatingPar *HeatingParStruct, hotwaterPar *HotwaterParStruct) (string, error) {
+	c.systemsCache.ResetKey(systemId)
+	state, err := c.GetSystemCtx(ctx, systemId)
 	if err != nil {
 		return "", err
 	}
@@ -361,53 +625,58 @@ func (c *Controller) StartStrategybased(systemId string, strategy int, heatingPa
 
 	switch whichQuickMode {
 	case 1:
-		err = c.StartHotWaterBoost(systemId, hotwaterPar.Index)
+		err = c.StartHotWaterBoostCtx(ctx, systemId, hotwaterPar.Index)
 		if err == nil {
 			c.currentQuickmode = QUICKMODE_HOTWATER
-			c.quickmodeStarted = time.Now()
+			c.quickmodeStarted = c.clock.Now()
 			c.debug("Starting hotwater boost")
 			c.quickModeExpiresAt = ""
 		}
 	case 2:
-		err = c.StartZoneQuickVeto(systemId, heatingPar.ZoneIndex, heatingPar.VetoSetpoint, heatingPar.VetoDuration)
+		err = c.StartZoneQuickVetoCtx(ctx, systemId, heatingPar.ZoneIndex, heatingPar.VetoSetpoint, heatingPar.VetoDuration)
 		if err == nil {
 			c.currentQuickmode = QUICKMODE_HEATING
-			c.quickmodeStarted = time.Now()
+			c.quickmodeStarted = c.clock.Now()
 			c.debug("Starting zone quick veto")
 			if heatingPar.VetoDuration < 0.0 {
-				c.quickModeExpiresAt = (time.Now().Add(time.Duration(int64(ZONEVETODURATION_DEFAULT*60) * int64(time.Minute)))).Format("15:04")
+				c.quickModeExpiresAt = (c.clock.Now().Add(time.Duration(int64(ZONEVETODURATION_DEFAULT*60) * int64(time.Minute)))).Format("15:04")
 			} else {
-				c.quickModeExpiresAt = (time.Now().Add(time.Duration(int64(heatingPar.VetoDuration*60) * int64(time.Minute)))).Format("15:04")
+				c.quickModeExpiresAt = (c.clock.Now().Add(time.Duration(int64(heatingPar.VetoDuration*60) * int64(time.Minute)))).Format("15:04")
 			}
 		}
 	default:
 		if c.currentQuickmode == QUICKMODE_HOTWATER {
 			// if hotwater boost active, then stop it
-			err = c.StopHotWaterBoost(systemId, hotwaterPar.Index)
+			err = c.StopHotWaterBoostCtx(ctx, systemId, hotwaterPar.Index)
 			if err == nil {
 				c.debug("Stopping hotwater boost")
 			}
 		}
 		if c.currentQuickmode == QUICKMODE_HEATING {
 			// if zone quick veto active, then stop it
-			err = c.StopZoneQuickVeto(systemId, heatingPar.ZoneIndex)
+			err = c.StopZoneQuickVetoCtx(ctx, systemId, heatingPar.ZoneIndex)
 			if err == nil {
 				c.debug("Stopping zone quick veto")
 			}
 		}
 		c.currentQuickmode = QUICKMODE_NOTHING
-		c.quickmodeStarted = time.Now()
-		c.quickModeExpiresAt = (time.Now().Add(time.Duration(10 * time.Minute))).Format("15:04")
+		c.quickmodeStarted = c.clock.Now()
+		c.quickModeExpiresAt = (c.clock.Now().Add(c.durations.idleMode)).Format("15:04")
 		c.debug("Enable called but no quick mode possible. Starting idle mode")
 	}
 
-	c.systemsCache.Reset()
+	c.systemsCache.ResetKey(systemId)
 	return c.currentQuickmode, err
 }
 
 func (c *Controller) StopStrategybased(systemId string, heatingPar *HeatingParStruct, hotwaterPar *HotwaterParStruct) (string, error) {
-	c.systemsCache.Reset()
-	state, err := c.GetSystem(systemId)
+	return c.StopStrategybasedCtx(context.Background(), systemId, heatingPar, hotwaterPar)
+}
+
+// StopStrategybasedCtx is like StopStrategybased, but the http requests are bound to ctx
+func (c *Controller) StopStrategybasedCtx(ctx context.Context, systemId string, heatingPar *HeatingParStruct, hotwaterPar *HotwaterParStruct) (string, error) {
+	c.systemsCache.ResetKey(systemId)
+	state, err := c.GetSystemCtx(ctx, systemId)
 	if err != nil {
 		return "", err
 	}
@@ -429,12 +698,12 @@ func (c *Controller) StopStrategybased(systemId string, heatingPar *HeatingParSt
 
 	switch c.currentQuickmode {
 	case QUICKMODE_HOTWATER:
-		err = c.StopHotWaterBoost(systemId, hotwaterPar.Index)
+		err = c.StopHotWaterBoostCtx(ctx, systemId, hotwaterPar.Index)
 		if err == nil {
 			c.debug(fmt.Sprint("Stopping quick mode", c.currentQuickmode))
 		}
 	case QUICKMODE_HEATING:
-		err = c.StopZoneQuickVeto(systemId, heatingPar.ZoneIndex)
+		err = c.StopZoneQuickVetoCtx(ctx, systemId, heatingPar.ZoneIndex)
 		if err == nil {
 			c.debug("Stopping zone quick veto")
 		}
@@ -445,9 +714,9 @@ func (c *Controller) StopStrategybased(systemId string, heatingPar *HeatingParSt
 	}
 	c.currentQuickmode = ""
 	c.quickModeExpiresAt = ""
-	c.quickmodeStopped = time.Now()
+	c.quickmodeStopped = c.clock.Now()
 
-	c.systemsCache.Reset()
+	c.systemsCache.ResetKey(systemId)
 	return c.currentQuickmode, err
 }
 
@@ -464,19 +733,19 @@ func (c *Controller) WhichQuickMode(dhwData *DhwData, domesticHotWaterData *Dome
 	hotWaterBoostPossible := false
 	if dhwData != nil {
 		if dhwData.State.CurrentDhwTemperature < dhwData.Configuration.TappingSetpoint+addOn &&
-			dhwData.Configuration.OperationModeDhw == OPERATIONMODE_TIME_CONTROLLED {
+			isTimeControlled(dhwData.Configuration.OperationModeDhw) {
 			hotWaterBoostPossible = true
 		}
 	}
 	if domesticHotWaterData != nil {
 		if domesticHotWaterData.State.CurrentDomesticHotWaterTemperature < domesticHotWaterData.Configuration.TappingSetpoint+addOn &&
-			domesticHotWaterData.Configuration.OperationModeDomesticHotWater == OPERATIONMODE_TIME_CONTROLLED {
+			isTimeControlled(domesticHotWaterData.Configuration.OperationModeDomesticHotWater) {
 			hotWaterBoostPossible = true
 		}
 	}
 	heatingQuickVetoPossible := false
 	if zoneData != nil {
-		if zoneData.Configuration.Heating.OperationModeHeating == OPERATIONMODE_TIME_CONTROLLED {
+		if isTimeControlled(zoneData.Configuration.Heating.OperationModeHeating) {
 			heatingQuickVetoPossible = true
 		}
 	}
@@ -508,3 +777,588 @@ func (c *Controller) WhichQuickMode(dhwData *DhwData, domesticHotWaterData *Dome
 	}
 	return whichQuickMode
 }
+
+// Sets the heating time program of a zone. The time program is validated against the MetaInfo of the current heating time program of the zone.
+func (c *Controller) SetZoneTimeProgram(systemId string, zone int, timeProgram TimeProgram) error {
+	return c.SetZoneTimeProgramCtx(context.Background(), systemId, zone, timeProgram)
+}
+
+// SetZoneTimeProgramCtx is like SetZoneTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetZoneTimeProgramCtx(ctx context.Context, systemId string, zone int, timeProgram TimeProgram) error {
+	zoneData, err := c.getZoneData(ctx, systemId, zone)
+	if err != nil {
+		return err
+	}
+	timeProgram.MetaInfo = zoneData.Configuration.Heating.TimeProgramHeating.MetaInfo
+
+	err = c.conn.SetZoneTimeProgramCtx(ctx, systemId, zone, timeProgram)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the cooling time program of a zone. The time program is validated against the MetaInfo of the current cooling time program of the zone.
+// If cooling is not allowed for the zone, ErrCoolingNotAllowed is returned.
+func (c *Controller) SetZoneCoolingTimeProgram(systemId string, zone int, timeProgram TimeProgram) error {
+	return c.SetZoneCoolingTimeProgramCtx(context.Background(), systemId, zone, timeProgram)
+}
+
+// SetZoneCoolingTimeProgramCtx is like SetZoneCoolingTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetZoneCoolingTimeProgramCtx(ctx context.Context, systemId string, zone int, timeProgram TimeProgram) error {
+	zoneData, err := c.getCoolingZoneData(ctx, systemId, zone)
+	if err != nil {
+		return err
+	}
+	timeProgram.MetaInfo = zoneData.Configuration.Cooling.TimeProgramCooling.MetaInfo
+
+	err = c.conn.SetZoneCoolingTimeProgramCtx(ctx, systemId, zone, timeProgram)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the time program of the domestic hot water. The time program is validated against the MetaInfo of the current time program.
+func (c *Controller) SetHotWaterTimeProgram(systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.SetHotWaterTimeProgramCtx(context.Background(), systemId, hotwaterIndex, timeProgram)
+}
+
+// SetHotWaterTimeProgramCtx is like SetHotWaterTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetHotWaterTimeProgramCtx(ctx context.Context, systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	current, _, err := c.hotWaterTimePrograms(ctx, systemId, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	timeProgram.MetaInfo = current.MetaInfo
+
+	err = c.conn.SetHotWaterTimeProgramCtx(ctx, systemId, hotwaterIndex, timeProgram)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the time program of the circulation pump. The time program is validated against the MetaInfo of the current time program.
+func (c *Controller) SetCirculationPumpTimeProgram(systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	return c.SetCirculationPumpTimeProgramCtx(context.Background(), systemId, hotwaterIndex, timeProgram)
+}
+
+// SetCirculationPumpTimeProgramCtx is like SetCirculationPumpTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetCirculationPumpTimeProgramCtx(ctx context.Context, systemId string, hotwaterIndex int, timeProgram TimeProgram) error {
+	_, current, err := c.hotWaterTimePrograms(ctx, systemId, hotwaterIndex)
+	if err != nil {
+		return err
+	}
+	timeProgram.MetaInfo = current.MetaInfo
+
+	err = c.conn.SetCirculationPumpTimeProgramCtx(ctx, systemId, hotwaterIndex, timeProgram)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// hotWaterTimePrograms returns the current hot water and circulation pump time programs from either "dhw" or "domesticHotWater"
+func (c *Controller) hotWaterTimePrograms(ctx context.Context, systemId string, hotwaterIndex int) (TimeProgram, TimeProgram, error) {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return TimeProgram{}, TimeProgram{}, err
+	}
+	if hasDhw(state, hotwaterIndex) {
+		dhwData := GetDhwData(state, hotwaterIndex)
+		return dhwData.Configuration.TimeProgramDhw, dhwData.Configuration.TimeProgramCirculationPump, nil
+	}
+	if hasDomesticHotWater(state, hotwaterIndex) {
+		domesticHotWaterData := GetDomesticHotWaterData(state, hotwaterIndex)
+		return domesticHotWaterData.Configuration.TimeProgramDomesticHotWater, domesticHotWaterData.Configuration.TimeProgramCirculationPump, nil
+	}
+	return TimeProgram{}, TimeProgram{}, fmt.Errorf("no hot water %d found for system %s", hotwaterIndex, systemId)
+}
+
+// checkHotWater returns an error if the system has neither "dhw" nor "domesticHotWater" with hotwaterIndex
+func (c *Controller) checkHotWater(ctx context.Context, systemId string, hotwaterIndex int) error {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	if !hasDhw(state, hotwaterIndex) && !hasDomesticHotWater(state, hotwaterIndex) {
+		return fmt.Errorf("no hot water %d found for system %s", hotwaterIndex, systemId)
+	}
+	return nil
+}
+
+// Sets the heating operation mode of a zone
+func (c *Controller) SetZoneOperationMode(systemId string, zone int, mode OperationMode) error {
+	return c.SetZoneOperationModeCtx(context.Background(), systemId, zone, mode)
+}
+
+// SetZoneOperationModeCtx is like SetZoneOperationMode, but the http requests are bound to ctx
+func (c *Controller) SetZoneOperationModeCtx(ctx context.Context, systemId string, zone int, mode OperationMode) error {
+	if _, err := c.getZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.SetZoneOperationModeCtx(ctx, systemId, zone, mode)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the cooling operation mode of a zone. If cooling is not allowed for the zone, ErrCoolingNotAllowed is returned.
+func (c *Controller) SetZoneCoolingOperationMode(systemId string, zone int, mode OperationMode) error {
+	return c.SetZoneCoolingOperationModeCtx(context.Background(), systemId, zone, mode)
+}
+
+// SetZoneCoolingOperationModeCtx is like SetZoneCoolingOperationMode, but the http requests are bound to ctx
+func (c *Controller) SetZoneCoolingOperationModeCtx(ctx context.Context, systemId string, zone int, mode OperationMode) error {
+	if _, err := c.getCoolingZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.SetZoneCoolingOperationModeCtx(ctx, systemId, zone, mode)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the operation mode of the domestic hot water
+func (c *Controller) SetHotWaterOperationMode(systemId string, hotwaterIndex int, mode OperationMode) error {
+	return c.SetHotWaterOperationModeCtx(context.Background(), systemId, hotwaterIndex, mode)
+}
+
+// SetHotWaterOperationModeCtx is like SetHotWaterOperationMode, but the http requests are bound to ctx
+func (c *Controller) SetHotWaterOperationModeCtx(ctx context.Context, systemId string, hotwaterIndex int, mode OperationMode) error {
+	if err := c.checkHotWater(ctx, systemId, hotwaterIndex); err != nil {
+		return err
+	}
+
+	err := c.conn.SetHotWaterOperationModeCtx(ctx, systemId, hotwaterIndex, mode)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the tapping setpoint of the domestic hot water. If the setpoint is outside of MinSetpoint and MaxSetpoint
+// of the hot water properties, an OutOfRangeError is returned.
+func (c *Controller) SetHotWaterSetpoint(systemId string, hotwaterIndex int, setpoint float64) error {
+	return c.SetHotWaterSetpointCtx(context.Background(), systemId, hotwaterIndex, setpoint)
+}
+
+// SetHotWaterSetpointCtx is like SetHotWaterSetpoint, but the http requests are bound to ctx
+func (c *Controller) SetHotWaterSetpointCtx(ctx context.Context, systemId string, hotwaterIndex int, setpoint float64) error {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	var minSetpoint, maxSetpoint float64
+	if hasDhw(state, hotwaterIndex) {
+		dhwData := GetDhwData(state, hotwaterIndex)
+		minSetpoint, maxSetpoint = dhwData.Properties.MinSetpoint, dhwData.Properties.MaxSetpoint
+	} else if hasDomesticHotWater(state, hotwaterIndex) {
+		domesticHotWaterData := GetDomesticHotWaterData(state, hotwaterIndex)
+		minSetpoint, maxSetpoint = domesticHotWaterData.Properties.MinSetpoint, domesticHotWaterData.Properties.MaxSetpoint
+	} else {
+		return fmt.Errorf("no hot water %d found for system %s", hotwaterIndex, systemId)
+	}
+	if minSetpoint == 0 && maxSetpoint == 0 {
+		minSetpoint, maxSetpoint = HOTWATERSETPOINT_MIN, HOTWATERSETPOINT_MAX
+	}
+	if err := checkRange("hot water setpoint", setpoint, minSetpoint, maxSetpoint); err != nil {
+		return err
+	}
+
+	err = c.conn.SetHotWaterSetpointCtx(ctx, systemId, hotwaterIndex, setpoint)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the setpoint of a zone for the operation mode OPERATIONMODE_MANUAL
+func (c *Controller) SetZoneManualModeSetpoint(systemId string, zone int, setpoint float64) error {
+	return c.SetZoneManualModeSetpointCtx(context.Background(), systemId, zone, setpoint)
+}
+
+// SetZoneManualModeSetpointCtx is like SetZoneManualModeSetpoint, but the http requests are bound to ctx
+func (c *Controller) SetZoneManualModeSetpointCtx(ctx context.Context, systemId string, zone int, setpoint float64) error {
+	if _, err := c.getZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.SetZoneManualModeSetpointCtx(ctx, systemId, zone, setpoint)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the cooling setpoint of a zone. If cooling is not allowed for the zone, ErrCoolingNotAllowed is returned.
+func (c *Controller) SetZoneCoolingSetpoint(systemId string, zone int, setpoint float64) error {
+	return c.SetZoneCoolingSetpointCtx(context.Background(), systemId, zone, setpoint)
+}
+
+// SetZoneCoolingSetpointCtx is like SetZoneCoolingSetpoint, but the http requests are bound to ctx
+func (c *Controller) SetZoneCoolingSetpointCtx(ctx context.Context, systemId string, zone int, setpoint float64) error {
+	if _, err := c.getCoolingZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.SetZoneCoolingSetpointCtx(ctx, systemId, zone, setpoint)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Starts a quick veto with setpoint as cooling target. If cooling is not allowed for the zone, ErrCoolingNotAllowed is returned.
+// Unlike StartZoneQuickVeto, the quick mode of the controller is not changed.
+func (c *Controller) StartZoneCoolingQuickVeto(systemId string, zone int, setpoint float32, duration float32) error {
+	return c.StartZoneCoolingQuickVetoCtx(context.Background(), systemId, zone, setpoint, duration)
+}
+
+// StartZoneCoolingQuickVetoCtx is like StartZoneCoolingQuickVeto, but the http requests are bound to ctx
+func (c *Controller) StartZoneCoolingQuickVetoCtx(ctx context.Context, systemId string, zone int, setpoint float32, duration float32) error {
+	if _, err := c.getCoolingZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.StartZoneCoolingQuickVetoCtx(ctx, systemId, zone, setpoint, duration)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// getZoneData returns the zone data or an error if the system has no zone with index zone
+func (c *Controller) getZoneData(ctx context.Context, systemId string, zone int) (*ZoneData, error) {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return nil, err
+	}
+	if !hasZone(state, zone) {
+		return nil, fmt.Errorf("no zone %d found for system %s", zone, systemId)
+	}
+	return GetZoneData(state, zone), nil
+}
+
+// getCoolingZoneData returns the zone data and ErrCoolingNotAllowed if cooling is not allowed for the zone
+func (c *Controller) getCoolingZoneData(ctx context.Context, systemId string, zone int) (*ZoneData, error) {
+	zoneData, err := c.getZoneData(ctx, systemId, zone)
+	if err != nil {
+		return nil, err
+	}
+	if !zoneData.Properties.IsCoolingAllowed {
+		return nil, fmt.Errorf("%w: zone %d of system %s", ErrCoolingNotAllowed, zone, systemId)
+	}
+	return zoneData, nil
+}
+
+// Sets the set-back temperature of a zone
+func (c *Controller) SetZoneSetBackTemperature(systemId string, zone int, temperature float64) error {
+	return c.SetZoneSetBackTemperatureCtx(context.Background(), systemId, zone, temperature)
+}
+
+// SetZoneSetBackTemperatureCtx is like SetZoneSetBackTemperature, but the http requests are bound to ctx
+func (c *Controller) SetZoneSetBackTemperatureCtx(ctx context.Context, systemId string, zone int, temperature float64) error {
+	if _, err := c.getZoneData(ctx, systemId, zone); err != nil {
+		return err
+	}
+
+	err := c.conn.SetZoneSetBackTemperatureCtx(ctx, systemId, zone, temperature)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Returns the index of the heating circuit the zone is associated with
+func (c *Controller) GetZoneCircuitIndex(systemId string, zone int) (int, error) {
+	return c.GetZoneCircuitIndexCtx(context.Background(), systemId, zone)
+}
+
+// GetZoneCircuitIndexCtx is like GetZoneCircuitIndex, but the http requests are bound to ctx
+func (c *Controller) GetZoneCircuitIndexCtx(ctx context.Context, systemId string, zone int) (int, error) {
+	state, err := c.GetSystemCtx(ctx, systemId)
+	if ignoreStale(err) != nil {
+		return -1, err
+	}
+	circuit, ok := GetZoneCircuitIndex(state, zone)
+	if !ok {
+		return -1, fmt.Errorf("no zone %d found for system %s", zone, systemId)
+	}
+	return circuit, err
+}
+
+// Sets the heating curve of a heating circuit
+func (c *Controller) SetCircuitHeatingCurve(systemId string, circuit int, heatingCurve float64) error {
+	return c.SetCircuitHeatingCurveCtx(context.Background(), systemId, circuit, heatingCurve)
+}
+
+// SetCircuitHeatingCurveCtx is like SetCircuitHeatingCurve, but the http requests are bound to ctx
+func (c *Controller) SetCircuitHeatingCurveCtx(ctx context.Context, systemId string, circuit int, heatingCurve float64) error {
+	if _, err := c.getCircuitData(ctx, systemId, circuit); err != nil {
+		return err
+	}
+
+	err := c.conn.SetCircuitHeatingCurveCtx(ctx, systemId, circuit, heatingCurve)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the minimum flow temperature setpoint of a heating circuit. It must not be above the current maximum flow temperature setpoint.
+func (c *Controller) SetCircuitMinFlowTemperature(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitMinFlowTemperatureCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitMinFlowTemperatureCtx is like SetCircuitMinFlowTemperature, but the http requests are bound to ctx
+func (c *Controller) SetCircuitMinFlowTemperatureCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	circuitData, err := c.getCircuitData(ctx, systemId, circuit)
+	if err != nil {
+		return err
+	}
+	maxTemperature := circuitData.Configuration.HeatingFlowTemperatureMaximumSetpoint
+	if maxTemperature == 0 {
+		maxTemperature = FLOWTEMPERATURE_MAX
+	} // if the system reports no maximum, only the general limit is checked
+	if err := checkRange("minimum flow temperature", temperature, FLOWTEMPERATURE_MIN, maxTemperature); err != nil {
+		return err
+	}
+
+	err = c.conn.SetCircuitMinFlowTemperatureCtx(ctx, systemId, circuit, temperature)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the maximum flow temperature setpoint of a heating circuit. It must not be below the current minimum flow temperature setpoint.
+func (c *Controller) SetCircuitMaxFlowTemperature(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitMaxFlowTemperatureCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitMaxFlowTemperatureCtx is like SetCircuitMaxFlowTemperature, but the http requests are bound to ctx
+func (c *Controller) SetCircuitMaxFlowTemperatureCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	circuitData, err := c.getCircuitData(ctx, systemId, circuit)
+	if err != nil {
+		return err
+	}
+	minTemperature := max(circuitData.Configuration.HeatingFlowTemperatureMinimumSetpoint, FLOWTEMPERATURE_MIN)
+	if err := checkRange("maximum flow temperature", temperature, minTemperature, FLOWTEMPERATURE_MAX); err != nil {
+		return err
+	}
+
+	err = c.conn.SetCircuitMaxFlowTemperatureCtx(ctx, systemId, circuit, temperature)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the outside temperature above which the heating circuit does not demand heat
+func (c *Controller) SetCircuitHeatDemandLimit(systemId string, circuit int, temperature float64) error {
+	return c.SetCircuitHeatDemandLimitCtx(context.Background(), systemId, circuit, temperature)
+}
+
+// SetCircuitHeatDemandLimitCtx is like SetCircuitHeatDemandLimit, but the http requests are bound to ctx
+func (c *Controller) SetCircuitHeatDemandLimitCtx(ctx context.Context, systemId string, circuit int, temperature float64) error {
+	if _, err := c.getCircuitData(ctx, systemId, circuit); err != nil {
+		return err
+	}
+
+	err := c.conn.SetCircuitHeatDemandLimitCtx(ctx, systemId, circuit, temperature)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+func (c *Controller) getCircuitData(ctx context.Context, systemId string, circuit int) (*CircuitData, error) {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCircuit(state, circuit) {
+		return nil, fmt.Errorf("no circuit %d found for system %s", circuit, systemId)
+	}
+	return GetCircuitData(state, circuit), nil
+}
+
+// Sets the operation mode of a ventilation
+func (c *Controller) SetVentilationOperationMode(systemId string, ventilationIndex int, mode OperationMode) error {
+	return c.SetVentilationOperationModeCtx(context.Background(), systemId, ventilationIndex, mode)
+}
+
+// SetVentilationOperationModeCtx is like SetVentilationOperationMode, but the http requests are bound to ctx
+func (c *Controller) SetVentilationOperationModeCtx(ctx context.Context, systemId string, ventilationIndex int, mode OperationMode) error {
+	if _, err := c.getVentilationData(ctx, systemId, ventilationIndex); err != nil {
+		return err
+	}
+
+	err := c.conn.SetVentilationOperationModeCtx(ctx, systemId, ventilationIndex, mode)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the maximum fan stage of a ventilation during the day.
+// The fan stage is checked against the maximum fan stage of the ventilation, if the system reports one.
+func (c *Controller) SetVentilationMaxDayFanStage(systemId string, ventilationIndex int, fanStage int) error {
+	return c.SetVentilationMaxDayFanStageCtx(context.Background(), systemId, ventilationIndex, fanStage)
+}
+
+// SetVentilationMaxDayFanStageCtx is like SetVentilationMaxDayFanStage, but the http requests are bound to ctx
+func (c *Controller) SetVentilationMaxDayFanStageCtx(ctx context.Context, systemId string, ventilationIndex int, fanStage int) error {
+	if err := c.checkVentilationFanStage(ctx, systemId, ventilationIndex, fanStage); err != nil {
+		return err
+	}
+
+	err := c.conn.SetVentilationMaxDayFanStageCtx(ctx, systemId, ventilationIndex, fanStage)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the maximum fan stage of a ventilation during the night.
+// The fan stage is checked against the maximum fan stage of the ventilation, if the system reports one.
+func (c *Controller) SetVentilationMaxNightFanStage(systemId string, ventilationIndex int, fanStage int) error {
+	return c.SetVentilationMaxNightFanStageCtx(context.Background(), systemId, ventilationIndex, fanStage)
+}
+
+// SetVentilationMaxNightFanStageCtx is like SetVentilationMaxNightFanStage, but the http requests are bound to ctx
+func (c *Controller) SetVentilationMaxNightFanStageCtx(ctx context.Context, systemId string, ventilationIndex int, fanStage int) error {
+	if err := c.checkVentilationFanStage(ctx, systemId, ventilationIndex, fanStage); err != nil {
+		return err
+	}
+
+	err := c.conn.SetVentilationMaxNightFanStageCtx(ctx, systemId, ventilationIndex, fanStage)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// Sets the time program of a ventilation. The time program is validated against the MetaInfo of the current time program of the ventilation.
+func (c *Controller) SetVentilationTimeProgram(systemId string, ventilationIndex int, timeProgram TimeProgram) error {
+	return c.SetVentilationTimeProgramCtx(context.Background(), systemId, ventilationIndex, timeProgram)
+}
+
+// SetVentilationTimeProgramCtx is like SetVentilationTimeProgram, but the http requests are bound to ctx
+func (c *Controller) SetVentilationTimeProgramCtx(ctx context.Context, systemId string, ventilationIndex int, timeProgram TimeProgram) error {
+	ventilationData, err := c.getVentilationData(ctx, systemId, ventilationIndex)
+	if err != nil {
+		return err
+	}
+	timeProgram.MetaInfo = ventilationData.Configuration.TimeProgramVentilation.MetaInfo
+
+	err = c.conn.SetVentilationTimeProgramCtx(ctx, systemId, ventilationIndex, timeProgram)
+	if err == nil {
+		c.systemsCache.ResetKey(systemId)
+	}
+	return err
+}
+
+// checkVentilationFanStage returns an OutOfRangeError if fanStage exceeds the maximum fan stage of the ventilation
+func (c *Controller) checkVentilationFanStage(ctx context.Context, systemId string, ventilationIndex int, fanStage int) error {
+	ventilationData, err := c.getVentilationData(ctx, systemId, ventilationIndex)
+	if err != nil {
+		return err
+	}
+	maxFanStage := ventilationData.Properties.MaximumFanStage
+	if maxFanStage <= 0 {
+		return nil
+	}
+	return checkRange("fan stage", float64(fanStage), FANSTAGE_MIN, float64(maxFanStage))
+}
+
+func (c *Controller) getVentilationData(ctx context.Context, systemId string, ventilationIndex int) (*VentilationData, error) {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVentilation(state, ventilationIndex) {
+		return nil, fmt.Errorf("no ventilation %d found for system %s", ventilationIndex, systemId)
+	}
+	return GetVentilationData(state, ventilationIndex), nil
+}
+
+// Sets a holiday for all zones and hot water circuits of systemId. During the holiday, the zones are heated to setpoint.
+func (c *Controller) SetHoliday(systemId string, start, end time.Time, setpoint float64) error {
+	return c.SetHolidayCtx(context.Background(), systemId, start, end, setpoint)
+}
+
+// SetHolidayCtx is like SetHoliday, but the http requests are bound to ctx
+func (c *Controller) SetHolidayCtx(ctx context.Context, systemId string, start, end time.Time, setpoint float64) error {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	err = c.conn.setHoliday(ctx, systemId, &state, start, end, setpoint)
+	c.systemsCache.ResetKey(systemId) // also after an error, as the holiday may already be set for some zones
+	return err
+}
+
+// Cancels the holiday of all zones and hot water circuits of systemId
+func (c *Controller) CancelHoliday(systemId string) error {
+	return c.CancelHolidayCtx(context.Background(), systemId)
+}
+
+// CancelHolidayCtx is like CancelHoliday, but the http requests are bound to ctx
+func (c *Controller) CancelHolidayCtx(ctx context.Context, systemId string) error {
+	state, err := c.getSystem(ctx, systemId)
+	if err != nil {
+		return err
+	}
+	err = c.conn.cancelHoliday(ctx, systemId, &state)
+	c.systemsCache.ResetKey(systemId) // also after an error, as the holiday may already be cancelled for some zones
+	return err
+}
+
+// Returns whether a holiday is currently active for any zone or hot water circuit of systemId.
+// If no holiday is active, the next planned holiday is reported (if any).
+func (c *Controller) GetHolidayStatus(systemId string) (HolidayStatus, error) {
+	return c.GetHolidayStatusCtx(context.Background(), systemId)
+}
+
+// GetHolidayStatusCtx is like GetHolidayStatus, but the http requests are bound to ctx
+func (c *Controller) GetHolidayStatusCtx(ctx context.Context, systemId string) (HolidayStatus, error) {
+	var status HolidayStatus
+	state, err := c.GetSystemCtx(ctx, systemId)
+	if ignoreStale(err) != nil {
+		return status, err
+	}
+
+	now := c.clock.Now()
+	check := func(start, end time.Time, setpoint float64) {
+		if start.IsZero() || end.IsZero() || !end.After(now) || status.Active {
+			return
+		}
+		if !start.After(now) {
+			status = HolidayStatus{Active: true, Start: start, End: end, Setpoint: setpoint}
+		} else if !status.Planned || start.Before(status.Start) {
+			status = HolidayStatus{Planned: true, Start: start, End: end, Setpoint: setpoint}
+		}
+	}
+	for _, zone := range state.Configuration.Zones {
+		check(zone.General.HolidayStartDateTime, zone.General.HolidayEndDateTime, zone.General.HolidaySetpoint)
+	}
+	for _, dhw := range state.Configuration.Dhw {
+		check(dhw.HolidayStartDateTime, dhw.HolidayEndDateTime, 0)
+	}
+	for _, domesticHotWater := range state.Configuration.DomesticHotWater {
+		check(domesticHotWater.HolidayStartDateTime, domesticHotWater.HolidayEndDateTime, 0)
+	}
+	return status, err
+}
+
+// isTimeControlled returns true for the time controlled operation modes of TLI (OPERATIONMODE_TIME_CONTROLLED) and VRC700 systems (OPERATIONMODE_AUTO)
+func isTimeControlled(mode string) bool {
+	return mode == OPERATIONMODE_TIME_CONTROLLED || mode == OPERATIONMODE_AUTO
+}
@@ -0,0 +1,427 @@
+package sensonet
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"golang.org/x/oauth2"
+)
+
+const testSystemId = "system-1"
+
+// fakeAPI serves the homes and the system state of a single TLI system and accepts all changes
+type fakeAPI struct {
+	mux      sync.Mutex
+	failGet  bool
+	system   string
+	requests map[string]int
+}
+
+func newFakeAPI(system string) *fakeAPI {
+	return &fakeAPI{system: system, requests: make(map[string]int)}
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.Lock()
+	a.requests[r.Method+" "+r.URL.Path]++
+	failGet, system := a.failGet, a.system
+	a.mux.Unlock()
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if failGet {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/homes":
+		_, _ = io.WriteString(w, `[{"homeName":"home","systemId":"`+testSystemId+`","serialNumber":"21234","onlineState":"ONLINE","firmware":{"version":"1.2.3"}}]`)
+	case "/emf/v2/" + testSystemId + "/currentSystem":
+		_, _ = io.WriteString(w, `{"gateway":{"device_uuid":"gateway-1"}}`)
+	case "/systems/" + testSystemId + "/meta-info/control-identifier":
+		_, _ = io.WriteString(w, `{"controlIdentifier":"tli"}`)
+	case "/systems/" + testSystemId + "/tli":
+		_, _ = io.WriteString(w, system)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *fakeAPI) setFailGet(fail bool) {
+	a.mux.Lock()
+	a.failGet = fail
+	a.mux.Unlock()
+}
+
+func (a *fakeAPI) setSystem(system string) {
+	a.mux.Lock()
+	a.system = system
+	a.mux.Unlock()
+}
+
+func (a *fakeAPI) count(request string) int {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.requests[request]
+}
+
+// changes returns the number of requests that are no GET requests
+func (a *fakeAPI) changes() int {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	var n int
+	for request, count := range a.requests {
+		if !strings.HasPrefix(request, http.MethodGet+" ") {
+			n += count
+		}
+	}
+	return n
+}
+
+// newTestController returns a controller for api with a mock clock
+func newTestController(t *testing.T, api *fakeAPI, opts ...CtrlOption) (*Controller, *clock.Mock) {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	conn, err := NewConnection(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk := clock.NewMock()
+	clk.Set(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	ctrl, err := NewController(conn, append([]CtrlOption{WithClock(clk)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ctrl.Close)
+	return ctrl, clk
+}
+
+const testSystem = `{
+	"state": {"zones": [{"index": 0}]},
+	"properties": {"zones": [{"index": 0}]},
+	"configuration": {"zones": [{"index": 0, "heating": {
+		"operationModeHeating": "TIME_CONTROLLED",
+		"timeProgramHeating": {"metaInfo": {"minSlotsPerDay": 0, "maxSlotsPerDay": 3, "setpointRequiredPerSlot": true}}
+	}}]}
+}`
+
+func TestControllerServeStale(t *testing.T) {
+	api := newFakeAPI(testSystem)
+	ctrl, clk := newTestController(t, api, WithServeStaleData(false))
+
+	if _, err := ctrl.GetSystem(testSystemId); err != nil {
+		t.Fatal(err)
+	}
+
+	api.setFailGet(true)
+	clk.Add(time.Hour)
+
+	homes, err := ctrl.GetHomes()
+	var staleErr *StaleError
+	if !errors.As(err, &staleErr) {
+		t.Fatalf("GetHomes: got %v, want StaleError", err)
+	}
+	if len(homes) != 1 || homes[0].SystemID != testSystemId {
+		t.Errorf("GetHomes: got %v, want the stale homes", homes)
+	}
+	if staleErr.Age != time.Hour {
+		t.Errorf("age: got %v, want %v", staleErr.Age, time.Hour)
+	}
+
+	if _, err := ctrl.GetSystem(testSystemId); !errors.As(err, &staleErr) {
+		t.Fatalf("GetSystem: got %v, want StaleError", err)
+	}
+
+	// the setters use the configuration of the stale state
+	slots := []Setpoint{{StartTime: 360, EndTime: 480, Setpoint: 21}}
+	timeProgram := TimeProgram{Monday: slots, Tuesday: slots, Wednesday: slots, Thursday: slots, Friday: slots, Saturday: slots, Sunday: slots}
+	if err := ctrl.SetZoneTimeProgram(testSystemId, 0, timeProgram); err != nil {
+		t.Fatalf("SetZoneTimeProgram with stale state: %v", err)
+	}
+	if api.count("PATCH /systems/"+testSystemId+"/tli/zones/0/time-windows") != 1 {
+		t.Error("time program was not sent")
+	}
+
+	// the meta info of the stale state requires a setpoint per slot
+	timeProgram.Monday = []Setpoint{{StartTime: 360, EndTime: 480}}
+	if err := ctrl.SetZoneTimeProgram(testSystemId, 0, timeProgram); !errors.Is(err, ErrInvalidTimeProgram) {
+		t.Errorf("SetZoneTimeProgram without setpoint: got %v, want ErrInvalidTimeProgram", err)
+	}
+}
+
+func TestControllerUnknownSystem(t *testing.T) {
+	api := newFakeAPI(testSystem)
+	ctrl, _ := newTestController(t, api)
+
+	if _, err := ctrl.GetSystem("unknown"); err == nil {
+		t.Fatal("expected error for unknown system")
+	}
+	if api.count("GET /systems/unknown/tli") != 0 {
+		t.Error("unknown system was requested")
+	}
+	ctrl.systemsCache.mux.Lock()
+	n := len(ctrl.systemsCache.caches)
+	ctrl.systemsCache.mux.Unlock()
+	if n != 0 {
+		t.Errorf("got %d cached systems, want 0", n)
+	}
+}
+
+func TestControllerGateway(t *testing.T) {
+	api := newFakeAPI(testSystem)
+	ctrl, clk := newTestController(t, api, WithDevicesCacheDuration(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		systemDevices, err := ctrl.GetSystemDevices(testSystemId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gateway := systemDevices.Gateway; gateway.FirmwareVersion != "1.2.3" || gateway.OnlineState != "ONLINE" || gateway.DeviceSerialNumber != "21234" {
+			t.Errorf("gateway was not completed from the homes: %+v", gateway)
+		}
+		// the devices are updated, but the homes are taken from their cache
+		clk.Add(2 * time.Minute)
+	}
+	if n := api.count("GET /emf/v2/" + testSystemId + "/currentSystem"); n != 2 {
+		t.Errorf("got %d devices requests, want 2", n)
+	}
+	if n := api.count("GET /homes"); n != 1 {
+		t.Errorf("got %d homes requests, want 1", n)
+	}
+}
+
+const testSystemHotWaterBoost = `{
+	"state": {"zones": [{"index": 0}], "dhw": [{"index": 255, "currentSpecialFunction": "CYLINDER_BOOST"}]},
+	"configuration": {"zones": [{"index": 0}], "dhw": [{"index": 255}]}
+}`
+
+func TestControllerBackgroundRefreshQuickMode(t *testing.T) {
+	api := newFakeAPI(testSystem)
+	ctrl, clk := newTestController(t, api, WithServeStaleData(true))
+
+	if _, err := ctrl.GetSystem(testSystemId); err != nil {
+		t.Fatal(err)
+	}
+	if qm := ctrl.GetCurrentQuickMode(); qm != "" {
+		t.Fatalf("quick mode: got %q, want none", qm)
+	}
+
+	// the quick mode is taken from the state of the background update by the next caller
+	api.setSystem(testSystemHotWaterBoost)
+	clk.Add(2 * CACHE_DURATION_SYSTEMS * time.Second)
+	if _, err := ctrl.GetSystem(testSystemId); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		_, _ = ctrl.GetSystem(testSystemId)
+		return ctrl.GetCurrentQuickMode() == QUICKMODE_HOTWATER
+	})
+}
+
+const testSystemQuickMode = `{
+	"state": {"zones": [{"index": 0}], "dhw": [{"index": 255, "currentDhwTemperature": 55}]},
+	"configuration": {
+		"zones": [{"index": 0, "heating": {"operationModeHeating": "TIME_CONTROLLED"}}],
+		"dhw": [{"index": 255, "operationModeDhw": "TIME_CONTROLLED", "tappingSetpoint": 50}]
+	}
+}`
+
+func TestControllerQuickModeTiming(t *testing.T) {
+	api := newFakeAPI(testSystemQuickMode)
+	ctrl, clk := newTestController(t, api)
+	heatingPar := &HeatingParStruct{ZoneIndex: 0, VetoSetpoint: 21, VetoDuration: 1.5}
+	hotwaterPar := &HotwaterParStruct{Index: -1}
+
+	quickMode := func(name string, want, wantExpiresAt string) {
+		t.Helper()
+		if _, err := ctrl.GetSystem(testSystemId); err != nil {
+			t.Fatal(err)
+		}
+		if got := ctrl.GetCurrentQuickMode(); got != want {
+			t.Errorf("%s: quick mode %q, want %q", name, got, want)
+		}
+		if got := ctrl.GetQuickModeExpiresAt(); got != wantExpiresAt {
+			t.Errorf("%s: expires at %q, want %q", name, got, wantExpiresAt)
+		}
+	}
+
+	// 12:00 the quick veto expires after VetoDuration hours
+	if qm, err := ctrl.StartStrategybased(testSystemId, STRATEGY_HEATING, heatingPar, hotwaterPar); err != nil || qm != QUICKMODE_HEATING {
+		t.Fatalf("start: got %q, %v, want %q", qm, err, QUICKMODE_HEATING)
+	}
+	if api.count("POST /systems/"+testSystemId+"/tli/zones/0/quick-veto") != 1 {
+		t.Error("quick veto was not started")
+	}
+	quickMode("quick veto", QUICKMODE_HEATING, "13:30")
+
+	if qm, err := ctrl.StopStrategybased(testSystemId, heatingPar, hotwaterPar); err != nil || qm != "" {
+		t.Fatalf("stop: got %q, %v, want none", qm, err)
+	}
+	quickMode("stopped", "", "")
+
+	// 12:05 the hot water is warm enough for a boost, so the idle mode is started
+	clk.Add(5 * time.Minute)
+	if qm, err := ctrl.StartStrategybased(testSystemId, STRATEGY_HOTWATER, heatingPar, hotwaterPar); err != nil || qm != QUICKMODE_NOTHING {
+		t.Fatalf("start: got %q, %v, want %q", qm, err, QUICKMODE_NOTHING)
+	}
+	quickMode("idle mode", QUICKMODE_NOTHING, "12:15")
+
+	// 12:10 the quick mode hysteresis has passed, but the idle mode is kept for IDLEMODE_DURATION
+	clk.Add(5 * time.Minute)
+	quickMode("idle mode before its end", QUICKMODE_NOTHING, "12:15")
+
+	// 12:16 the idle mode has ended and the system state without quick mode is taken over
+	clk.Add(6 * time.Minute)
+	quickMode("idle mode after its end", "", "12:15")
+}
+
+const testSystemSetters = `{
+	"state": {"zones": [{"index": 0}], "dhw": [{"index": 255}], "circuits": [{"index": 0}], "ventilations": [{"index": 0}]},
+	"properties": {"zones": [{"index": 0}], "dhw": [{"index": 255}], "circuits": [{"index": 0}], "ventilations": [{"index": 0}]},
+	"configuration": {
+		"zones": [{"index": 0}],
+		"circuits": [{"index": 0}],
+		"ventilations": [{"index": 0}],
+		"dhw": [{"index": 255, "timeProgramDhw": {"metaInfo": {"maxSlotsPerDay": 3}}}]
+	}
+}`
+
+func TestControllerSetterRequests(t *testing.T) {
+	slots := []Setpoint{{StartTime: 360, EndTime: 480}}
+	timeProgram := TimeProgram{Monday: slots, Tuesday: slots, Wednesday: slots, Thursday: slots, Friday: slots, Saturday: slots, Sunday: slots}
+
+	tests := []struct {
+		name    string
+		set     func(*Controller) error
+		request string // the expected request, empty if the setter must fail without request
+	}{
+		{
+			name:    "hot water time program",
+			set:     func(c *Controller) error { return c.SetHotWaterTimeProgram(testSystemId, -1, timeProgram) },
+			request: "PATCH /systems/" + testSystemId + "/tli/domestic-hot-water/255/time-windows",
+		},
+		{
+			name:    "circulation pump time program",
+			set:     func(c *Controller) error { return c.SetCirculationPumpTimeProgram(testSystemId, -1, timeProgram) },
+			request: "PATCH /systems/" + testSystemId + "/tli/domestic-hot-water/255/circulation-pump-time-windows",
+		},
+		{
+			name: "hot water time program of unknown index",
+			set:  func(c *Controller) error { return c.SetHotWaterTimeProgram(testSystemId, 1, timeProgram) },
+		},
+		{
+			name:    "zone operation mode",
+			set:     func(c *Controller) error { return c.SetZoneOperationMode(testSystemId, 0, OPERATIONMODE_MANUAL) },
+			request: "PATCH /systems/" + testSystemId + "/tli/zones/0/heating-operation-mode",
+		},
+		{
+			name: "zone operation mode of unknown zone",
+			set:  func(c *Controller) error { return c.SetZoneOperationMode(testSystemId, 1, OPERATIONMODE_MANUAL) },
+		},
+		{
+			name:    "hot water operation mode",
+			set:     func(c *Controller) error { return c.SetHotWaterOperationMode(testSystemId, -1, OPERATIONMODE_OFF) },
+			request: "PATCH /systems/" + testSystemId + "/tli/domestic-hot-water/255/operation-mode",
+		},
+		{
+			name: "hot water operation mode of unknown index",
+			set:  func(c *Controller) error { return c.SetHotWaterOperationMode(testSystemId, 1, OPERATIONMODE_OFF) },
+		},
+		{
+			name:    "zone manual mode setpoint",
+			set:     func(c *Controller) error { return c.SetZoneManualModeSetpoint(testSystemId, 0, 21) },
+			request: "PATCH /systems/" + testSystemId + "/tli/zones/0/manual-mode-setpoint",
+		},
+		{
+			name: "zone manual mode setpoint of unknown zone",
+			set:  func(c *Controller) error { return c.SetZoneManualModeSetpoint(testSystemId, 1, 21) },
+		},
+		{
+			name:    "zone set-back temperature",
+			set:     func(c *Controller) error { return c.SetZoneSetBackTemperature(testSystemId, 0, 17) },
+			request: "PATCH /systems/" + testSystemId + "/tli/zones/0/set-back-temperature",
+		},
+		{
+			name: "zone set-back temperature of unknown zone",
+			set:  func(c *Controller) error { return c.SetZoneSetBackTemperature(testSystemId, 1, 17) },
+		},
+		{
+			name:    "circuit heating curve",
+			set:     func(c *Controller) error { return c.SetCircuitHeatingCurve(testSystemId, 0, 1.2) },
+			request: "PATCH /systems/" + testSystemId + "/tli/circuits/0/heating-curve",
+		},
+		{
+			name: "heating curve of unknown circuit",
+			set:  func(c *Controller) error { return c.SetCircuitHeatingCurve(testSystemId, 1, 1.2) },
+		},
+		{
+			name:    "circuit heat demand limit",
+			set:     func(c *Controller) error { return c.SetCircuitHeatDemandLimit(testSystemId, 0, 20) },
+			request: "PATCH /systems/" + testSystemId + "/tli/circuits/0/heat-demand-limited-by-outside-temperature",
+		},
+		{
+			name: "heat demand limit of unknown circuit",
+			set:  func(c *Controller) error { return c.SetCircuitHeatDemandLimit(testSystemId, 1, 20) },
+		},
+		{
+			name: "ventilation operation mode",
+			set: func(c *Controller) error {
+				return c.SetVentilationOperationMode(testSystemId, 0, OPERATIONMODE_REDUCED)
+			},
+			request: "PATCH /systems/" + testSystemId + "/tli/ventilations/0/operation-mode",
+		},
+		{
+			name: "invalid ventilation operation mode",
+			set:  func(c *Controller) error { return c.SetVentilationOperationMode(testSystemId, 0, OPERATIONMODE_MANUAL) },
+		},
+		{
+			name: "operation mode of unknown ventilation",
+			set: func(c *Controller) error {
+				return c.SetVentilationOperationMode(testSystemId, 1, OPERATIONMODE_REDUCED)
+			},
+		},
+		{
+			name:    "ventilation time program",
+			set:     func(c *Controller) error { return c.SetVentilationTimeProgram(testSystemId, 0, timeProgram) },
+			request: "PATCH /systems/" + testSystemId + "/tli/ventilations/0/time-windows",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newFakeAPI(testSystemSetters)
+			ctrl, _ := newTestController(t, api)
+
+			err := tc.set(ctrl)
+			if tc.request == "" {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if n := api.changes(); n != 0 {
+					t.Errorf("got %d change requests, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := api.count(tc.request); n != 1 {
+				t.Errorf("%s: got %d requests, want 1", tc.request, n)
+			}
+		})
+	}
+}
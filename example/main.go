@@ -40,24 +40,6 @@ func readCredentials(filename string) (*sensonet.CredentialsStruct, error) {
 	return &creds, err
 }
 
-func readToken(filename string) (*oauth2.Token, error) {
-	b, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-	var token oauth2.Token
-	err = json.Unmarshal(b, &token)
-	return &token, err
-}
-
-func writeToken(filename string, token *oauth2.Token) error {
-	b, err := json.MarshalIndent(token, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(filename, b, 0o644)
-}
-
 func writeSystemDeviceInfo(filename string, systemDevices *sensonet.SystemDevices) error {
 	b, err := json.MarshalIndent(systemDevices, "", "  ")
 	if err != nil {
@@ -187,29 +169,24 @@ func main() {
 		fmt.Println("Read credentials from file")
 	}
 
-	fmt.Println("Second step: Reading token file")
-	// Read token from file if a token is already present and stored in a file
-	token, err := readToken(TOKEN_FILE)
-	if err != nil {
-		logger.Println("readToken() ended unsuccessful. Probably no token file was found. Error:", err)
-	} else {
-		logger.Println("Token read from file:", token)
-	}
-
-	fmt.Println("Third step: Generating new connection to be used for further calls of sensonet library")
+	fmt.Println("Second step: Preparing token source")
 
 	var client *http.Client
 	client = NewClient()
 
-	// If you have user, password and realm, use Oauth2ConfigForRealm() and PasswordCredentialsToken() to get a token
+	// If you have user, password and realm, use Oauth2ConfigForRealm() and StoredTokenSource() to get a token source.
+	// The token source reads the token from the token file if it is present, falls back to PasswordCredentialsToken() if necessary
+	// and writes every new token to the token file for future calls of this program.
 	ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, client)
 	clientCtx := context.WithValue(ctx, oauth2.HTTPClient, client)
 	oc := sensonet.Oauth2ConfigForRealm(credentials.Realm)
-	token, err = oc.PasswordCredentialsToken(clientCtx, credentials.User, credentials.Password)
-	if err != nil {
+	tokenSource := oc.StoredTokenSource(clientCtx, sensonet.NewFileTokenStore(TOKEN_FILE), credentials.User, credentials.Password)
+	if _, err := tokenSource.Token(); err != nil {
 		logger.Fatal(err)
 	}
 
+	fmt.Println("Third step: Generating new connection to be used for further calls of sensonet library")
+
 	// If http client logging is wanted, you have to prepare an http client with logging
 	if WITH_HTTP_CLIENT_LOGGING {
 		clientlogger := log.New(logFile, "client: ", log.Lshortfile)
@@ -220,9 +197,9 @@ func main() {
 	// You can provide an http client (especially one with logging) as optional parameter.
 	var conn *sensonet.Connection
 	if WITH_HTTP_CLIENT_LOGGING {
-		conn, err = sensonet.NewConnection(oc.TokenSource(clientCtx, token), sensonet.WithHttpClient(client))
+		conn, err = sensonet.NewConnection(tokenSource, sensonet.WithHttpClient(client))
 	} else {
-		conn, err = sensonet.NewConnection(oc.TokenSource(clientCtx, token))
+		conn, err = sensonet.NewConnection(tokenSource)
 	}
 	if err != nil {
 		logger.Fatal(err)
@@ -242,13 +219,6 @@ func main() {
 		logger.Fatal(err)
 	}
 
-	// Store the current token in a file for future calls of this program
-	if err := writeToken(TOKEN_FILE, token); err != nil {
-		logger.Fatal(err)
-	} else {
-		logger.Println("New Token written to file:", token)
-	}
-
 	fmt.Println("Fourth step: Reading Homes() structure from myVaillant portal")
 	homes, err := ctrl.GetHomes()
 	if err != nil {
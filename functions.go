@@ -54,6 +54,59 @@ func GetDomesticHotWaterData(state SystemStatus, index int) *DomesticHotWaterDat
 	return &domesticHotWaterData
 }
 
+// Value returns the first value of the live report with the given id (e.g. LIVEREPORT_TANK_TEMPERATURE)
+func (r LiveReport) Value(id string) (float64, bool) {
+	for _, device := range r.Devices {
+		for _, report := range device.Reports {
+			if report.ID == id {
+				return report.Value, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// TankTemperature returns the temperature of the domestic hot water tank in °C
+func (r LiveReport) TankTemperature() (float64, bool) {
+	return r.Value(LIVEREPORT_TANK_TEMPERATURE)
+}
+
+// FlowTemperature returns the flow temperature in °C
+func (r LiveReport) FlowTemperature() (float64, bool) {
+	return r.Value(LIVEREPORT_FLOW_TEMPERATURE)
+}
+
+// ReturnTemperature returns the return temperature in °C
+func (r LiveReport) ReturnTemperature() (float64, bool) {
+	return r.Value(LIVEREPORT_RETURN_TEMPERATURE)
+}
+
+// CompressorModulation returns the modulation of the compressor in percent
+func (r LiveReport) CompressorModulation() (float64, bool) {
+	return r.Value(LIVEREPORT_COMPRESSOR_MODULATION)
+}
+
+// FilterDiagnostics returns the diagnostics of the given type
+func FilterDiagnostics(diagnostics []Diagnostic, diagnosticType DiagnosticType) []Diagnostic {
+	var res []Diagnostic
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Type == diagnosticType {
+			res = append(res, diagnostic)
+		}
+	}
+	return res
+}
+
+// GetRoomData returns the room with the given index or nil
+func GetRoomData(rooms []Room, index int) *Room {
+	for i := range rooms {
+		if rooms[i].RoomIndex == index {
+			return &rooms[i]
+		}
+	}
+	return nil
+}
+
 func GetZoneData(state SystemStatus, index int) *ZoneData {
 	// Extracting correct State.Zones element
 	if len(state.State.Zones) == 0 {
@@ -80,3 +133,196 @@ func GetZoneData(state SystemStatus, index int) *ZoneData {
 	}
 	return &zoneData
 }
+
+func GetCircuitData(state SystemStatus, index int) *CircuitData {
+	// Extracting correct State.Circuits element
+	if len(state.State.Circuits) == 0 {
+		return nil
+	}
+	var circuitData CircuitData
+	for _, stateCircuit := range state.State.Circuits {
+		if stateCircuit.Index == index || (stateCircuit.Index == CIRCUITINDEX_DEFAULT && index < 0) {
+			circuitData.State = stateCircuit
+			break
+		}
+	}
+	for _, propCircuit := range state.Properties.Circuits {
+		if propCircuit.Index == index || (propCircuit.Index == CIRCUITINDEX_DEFAULT && index < 0) {
+			circuitData.Properties = propCircuit
+			break
+		}
+	}
+	for _, confCircuit := range state.Configuration.Circuits {
+		if confCircuit.Index == index || (confCircuit.Index == CIRCUITINDEX_DEFAULT && index < 0) {
+			circuitData.Configuration = confCircuit
+			break
+		}
+	}
+	return &circuitData
+}
+
+// hasDhw returns true if the configuration of state contains the dhw with index.
+// GetDhwData() returns empty data for an unknown index.
+func hasDhw(state SystemStatus, index int) bool {
+	for _, confDhw := range state.Configuration.Dhw {
+		if confDhw.Index == index || (confDhw.Index == HOTWATERINDEX_DEFAULT && index < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDomesticHotWater returns true if the configuration of state contains the domestic hot water with index.
+// GetDomesticHotWaterData() returns empty data for an unknown index.
+func hasDomesticHotWater(state SystemStatus, index int) bool {
+	for _, confDomesticHotWater := range state.Configuration.DomesticHotWater {
+		if confDomesticHotWater.Index == index || (confDomesticHotWater.Index == HOTWATERINDEX_DEFAULT && index < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasZone returns true if the configuration of state contains the zone with index.
+// GetZoneData() returns empty zone data for an unknown index.
+func hasZone(state SystemStatus, index int) bool {
+	for _, confZone := range state.Configuration.Zones {
+		if confZone.Index == index || (confZone.Index == ZONEINDEX_DEFAULT && index < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCircuit returns true if the configuration of state contains the heating circuit with index.
+// GetCircuitData() returns empty circuit data for an unknown index.
+func hasCircuit(state SystemStatus, index int) bool {
+	for _, confCircuit := range state.Configuration.Circuits {
+		if confCircuit.Index == index || (confCircuit.Index == CIRCUITINDEX_DEFAULT && index < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasVentilation returns true if the configuration of state contains the ventilation with index.
+// GetVentilationData() returns empty ventilation data for an unknown index.
+func hasVentilation(state SystemStatus, index int) bool {
+	for _, confVentilation := range state.Configuration.Ventilations {
+		if confVentilation.Index == index || (confVentilation.Index == VENTILATIONINDEX_DEFAULT && index < 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the index of the heating circuit the zone is associated with (from PropertiesZone.AssociatedCircuitIndex)
+func GetZoneCircuitIndex(state SystemStatus, zone int) (int, bool) {
+	for _, propZone := range state.Properties.Zones {
+		if propZone.Index == zone || (propZone.Index == ZONEINDEX_DEFAULT && zone < 0) {
+			return propZone.AssociatedCircuitIndex, true
+		}
+	}
+	return -1, false
+}
+
+// Returns true if the heating circuit associated with the zone is currently cooling
+func IsZoneCooling(state SystemStatus, zone int) bool {
+	circuit, ok := GetZoneCircuitIndex(state, zone)
+	if !ok {
+		return false
+	}
+	for _, stateCircuit := range state.State.Circuits {
+		if stateCircuit.Index == circuit {
+			return stateCircuit.CircuitState == CIRCUITSTATE_COOLING
+		}
+	}
+	return false
+}
+
+func GetVentilationData(state SystemStatus, index int) *VentilationData {
+	// Extracting correct State.Ventilations element
+	if len(state.State.Ventilations) == 0 {
+		return nil
+	}
+	var ventilationData VentilationData
+	for _, stateVentilation := range state.State.Ventilations {
+		if stateVentilation.Index == index || (stateVentilation.Index == VENTILATIONINDEX_DEFAULT && index < 0) {
+			ventilationData.State = stateVentilation
+			break
+		}
+	}
+	for _, propVentilation := range state.Properties.Ventilations {
+		if propVentilation.Index == index || (propVentilation.Index == VENTILATIONINDEX_DEFAULT && index < 0) {
+			ventilationData.Properties = propVentilation
+			break
+		}
+	}
+	for _, confVentilation := range state.Configuration.Ventilations {
+		if confVentilation.Index == index || (confVentilation.Index == VENTILATIONINDEX_DEFAULT && index < 0) {
+			ventilationData.Configuration = confVentilation
+			break
+		}
+	}
+	return &ventilationData
+}
+
+// Returns the devices of systemDevices selected by whichDevices (DEVICES_ALL, DEVICES_PRIMARY_HEATER, ...)
+func GetDevicesAndInfo(systemDevices SystemDevices, whichDevices int) []DeviceAndInfo {
+	var devices []DeviceAndInfo
+	var deviceAndInfo DeviceAndInfo
+	if systemDevices.PrimaryHeatGenerator.DeviceUUID != "" && (whichDevices == DEVICES_PRIMARY_HEATER || whichDevices == DEVICES_ALL) {
+		deviceAndInfo.Device = systemDevices.PrimaryHeatGenerator
+		deviceAndInfo.Info = "primary_heat_generator"
+		devices = append(devices, deviceAndInfo)
+	}
+	if whichDevices == DEVICES_SECONDARY_HEATER || whichDevices == DEVICES_ALL {
+		for _, secHeatGen := range systemDevices.SecondaryHeatGenerators {
+			deviceAndInfo.Device = secHeatGen
+			deviceAndInfo.Info = "secondary_heat_generator"
+			devices = append(devices, deviceAndInfo)
+		}
+	}
+
+	if systemDevices.ElectricBackupHeater.DeviceUUID != "" && (whichDevices == DEVICES_BACKUP_HEATER || whichDevices == DEVICES_ALL) {
+		deviceAndInfo.Device = systemDevices.ElectricBackupHeater
+		deviceAndInfo.Info = "electric_backup_heater"
+		devices = append(devices, deviceAndInfo)
+	}
+	if systemDevices.SolarStation.DeviceUUID != "" && (whichDevices == DEVICES_SOLAR_STATION || whichDevices == DEVICES_ALL) {
+		deviceAndInfo.Device = systemDevices.SolarStation
+		deviceAndInfo.Info = "solar_station"
+		devices = append(devices, deviceAndInfo)
+	}
+	if systemDevices.Ventilation.DeviceUUID != "" && (whichDevices == DEVICES_VENTILATION || whichDevices == DEVICES_ALL) {
+		deviceAndInfo.Device = systemDevices.Ventilation
+		deviceAndInfo.Info = "ventilation"
+		devices = append(devices, deviceAndInfo)
+	}
+	// The gateway is often reported without device uuid, but with firmware, online state or serial number
+	if systemDevices.Gateway.isPresent() && (whichDevices == DEVICES_GATEWAY || whichDevices == DEVICES_ALL) {
+		deviceAndInfo.Device = systemDevices.Gateway.Device
+		deviceAndInfo.Info = "gateway"
+		devices = append(devices, deviceAndInfo)
+	}
+	return devices
+}
+
+// completeGateway adds firmware, online state and serial number of the gateway from the home of systemId, if they are missing in the system devices
+func completeGateway(gateway *Gateway, homes Homes, systemId string) {
+	for _, home := range homes {
+		if home.SystemID != systemId {
+			continue
+		}
+		if gateway.FirmwareVersion == "" {
+			gateway.FirmwareVersion = home.Firmware.Version
+		}
+		if gateway.OnlineState == "" {
+			gateway.OnlineState = home.OnlineState
+		}
+		if gateway.DeviceSerialNumber == "" {
+			gateway.DeviceSerialNumber = home.SerialNumber
+		}
+		return
+	}
+}
@@ -37,17 +37,17 @@ func doBody(r *http.Client, req *http.Request) ([]byte, error) {
 	return body, err
 }
 
-// decodeJSON reads HTTP response and decodes JSON body if error is nil
+// decodeJSON reads HTTP response and decodes JSON body if error is nil.
+// The body of an unsuccessful response is not decoded into res, but returned as APIError.
 func decodeJSON(resp *http.Response, res interface{}) error {
 	if err := ResponseError(resp); err != nil {
-		_ = json.NewDecoder(resp.Body).Decode(&res)
 		return err
 	}
 	return json.NewDecoder(resp.Body).Decode(&res)
 }
 
 // doJSON executes HTTP request and decodes JSON response.
-// It returns a StatusError on response codes other than HTTP 2xx.
+// It returns an APIError on response codes other than HTTP 2xx.
 func doJSON(r *http.Client, req *http.Request, res interface{}) error {
 	resp, err := r.Do(req)
 	if err == nil {
@@ -76,15 +76,15 @@ func (e StatusError) StatusCode() int {
 	return e.resp.StatusCode
 }
 
-// ResponseError turns an HTTP status code into an error
+// ResponseError turns an HTTP status code into an APIError. The body of an unsuccessful response is read and closed.
 func ResponseError(resp *http.Response) error {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return StatusError{resp: resp}
+		return readAPIError(resp)
 	}
 	return nil
 }
 
-// ReadBody reads HTTP response and returns error on response codes other than HTTP 2xx. It closes the request body after reading.
+// ReadBody reads HTTP response and returns an APIError on response codes other than HTTP 2xx. It closes the request body after reading.
 func ReadBody(resp *http.Response) ([]byte, error) {
 	defer resp.Body.Close()
 	b, err := io.ReadAll(resp.Body)
@@ -92,7 +92,7 @@ func ReadBody(resp *http.Response) ([]byte, error) {
 		return []byte{}, err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return b, StatusError{resp: resp}
+		return b, newAPIError(resp, b)
 	}
 	return b, nil
 }
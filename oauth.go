@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
@@ -18,22 +19,64 @@ import (
 
 const REALM_GERMANY = "vaillant-germany-b2c"
 
+var (
+	// ErrInvalidCredentials indicates that username or password are wrong
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountLocked indicates that the account is disabled or temporarily locked, e.g. after too many failed logins
+	ErrAccountLocked = errors.New("account locked")
+	// ErrLoginFormChanged indicates that the login page could not be processed, probably because the page of the identity provider has changed
+	ErrLoginFormChanged = errors.New("login form changed")
+	// ErrConsentRequired indicates that the user has to accept the terms of service or to grant consent in the app or on the website
+	ErrConsentRequired = errors.New("consent required")
+)
+
+// LoginError is returned by PasswordCredentialsToken() if the login was refused.
+// Err is one of the sentinel errors above, Message is the message shown on the login page (if any).
+type LoginError struct {
+	Err     error
+	Message string
+}
+
+func (e *LoginError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("login failed: %v", e.Err)
+	}
+	return fmt.Sprintf("login failed: %v (%s)", e.Err, e.Message)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
 type Oauth2Config struct {
 	*oauth2.Config
 }
 
 func Oauth2ConfigForRealm(realm string) *Oauth2Config {
-	if realm == "" {
-		realm = REALM_GERMANY
-	}
+	return Oauth2ConfigForEndpoints(realm, EndpointsForBrand(BRAND_VAILLANT))
+}
+
+// Oauth2ConfigForEndpoints returns the oauth2 configuration for realm using the identity provider given by endpoints
+func Oauth2ConfigForEndpoints(realm string, endpoints Endpoints) *Oauth2Config {
+	profile := ProfileForBrand(BRAND_VAILLANT, "")
+	profile.Realm = realm
+	profile.Endpoints = endpoints
+	return Oauth2ConfigForProfile(profile)
+}
+
+// Oauth2ConfigForProfile returns the oauth2 configuration for the realm, client id and identity provider of profile
+// Empty fields of the profile are taken from ProfileForBrand() for the brand of the profile.
+func Oauth2ConfigForProfile(profile Profile) *Oauth2Config {
+	profile = profile.withDefaults()
+	realm := profile.Realm
 	return &Oauth2Config{
 		Config: &oauth2.Config{
-			ClientID: CLIENT_ID,
+			ClientID: profile.ClientID,
 			Endpoint: oauth2.Endpoint{
-				AuthURL:  fmt.Sprintf(AUTH_URL, realm),
-				TokenURL: fmt.Sprintf(TOKEN_URL, realm),
+				AuthURL:  profile.Endpoints.AuthURLBase + fmt.Sprintf(AUTH_PATH, realm),
+				TokenURL: profile.Endpoints.AuthURLBase + fmt.Sprintf(TOKEN_PATH, realm),
 			},
-			RedirectURL: REDIRECT_URL,
+			RedirectURL: profile.RedirectURL,
 			Scopes:      []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess},
 		},
 	}
@@ -66,7 +109,7 @@ func (oc *Oauth2Config) PasswordCredentialsToken(ctx context.Context, username s
 
 	match := regexp.MustCompile(`action\s*=\s*"(.+?)"`).FindStringSubmatch(string(body))
 	if len(match) < 2 {
-		return nil, errors.New("missing login form action")
+		return nil, &LoginError{Err: ErrLoginFormChanged, Message: "missing login form action"}
 	}
 	uri = match[1]
 
@@ -83,12 +126,57 @@ func (oc *Oauth2Config) PasswordCredentialsToken(ctx context.Context, username s
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
-	location, _ := url.Parse(resp.Header.Get("Location"))
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return nil, &LoginError{Err: ErrLoginFormChanged, Message: "invalid redirect location"}
+	}
 	code := location.Query().Get("code")
 	if code == "" {
-		return nil, errors.New("could not get code")
+		body, _ := io.ReadAll(resp.Body)
+		return nil, parseLoginError(location, string(body))
 	}
 
 	return oc.Exchange(ctx, code, oauth2.VerifierOption(cv))
 }
+
+var (
+	loginMessageRegex  = regexp.MustCompile(`(?s)<span[^>]*(?:id="input-error"|class="[^"]*kc-feedback-text[^"]*")[^>]*>(.*?)</span>`)
+	lockedMessageRegex = regexp.MustCompile(`(?i)disabled|locked|gesperrt|deaktiviert`)
+	consentPageRegex   = regexp.MustCompile(`id="kc-terms-text"|id="kc-oauth"|required-action`)
+	passwordFieldRegex = regexp.MustCompile(`name="password"`)
+)
+
+// parseLoginError classifies the response of the identity provider to the login form, when it did not contain a code
+func parseLoginError(location *url.URL, body string) error {
+	// required actions (e.g. terms and conditions) are announced by a redirect or by an error of the authorization request
+	if strings.Contains(location.Path, "required-action") {
+		return &LoginError{Err: ErrConsentRequired}
+	}
+	switch location.Query().Get("error") {
+	case "":
+	case "consent_required", "interaction_required":
+		return &LoginError{Err: ErrConsentRequired, Message: location.Query().Get("error_description")}
+	default:
+		return &LoginError{Err: ErrLoginFormChanged, Message: location.Query().Get("error_description")}
+	}
+
+	var message string
+	if match := loginMessageRegex.FindStringSubmatch(body); len(match) > 1 {
+		message = strings.TrimSpace(html.UnescapeString(match[1]))
+	}
+
+	switch {
+	case message != "" && lockedMessageRegex.MatchString(message):
+		return &LoginError{Err: ErrAccountLocked, Message: message}
+	case consentPageRegex.MatchString(body):
+		return &LoginError{Err: ErrConsentRequired, Message: message}
+	case message != "" && passwordFieldRegex.MatchString(body):
+		return &LoginError{Err: ErrInvalidCredentials, Message: message}
+	case message != "":
+		return &LoginError{Err: ErrLoginFormChanged, Message: message}
+	default:
+		return &LoginError{Err: ErrLoginFormChanged, Message: "could not get code"}
+	}
+}
@@ -0,0 +1,104 @@
+package sensonet
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseLoginError(t *testing.T) {
+	const loginForm = `<form id="kc-form-login" action="https://example.com/login"><input name="password" type="password"></form>`
+
+	tests := []struct {
+		name     string
+		location string
+		body     string
+		want     error
+		message  string
+	}{
+		{
+			name:     "invalid credentials",
+			location: "",
+			body:     `<span id="input-error" class="kc-feedback-text">Invalid username or password.</span>` + loginForm,
+			want:     ErrInvalidCredentials,
+			message:  "Invalid username or password.",
+		},
+		{
+			name:     "account locked",
+			location: "",
+			body:     `<span class="alert kc-feedback-text">Account is temporarily disabled.</span>` + loginForm,
+			want:     ErrAccountLocked,
+			message:  "Account is temporarily disabled.",
+		},
+		{
+			name:     "account locked in german",
+			location: "",
+			body:     `<span id="input-error">Ihr Konto ist gesperrt.</span>`,
+			want:     ErrAccountLocked,
+			message:  "Ihr Konto ist gesperrt.",
+		},
+		{
+			name:     "terms page",
+			location: "",
+			body:     `<div id="kc-terms-text">Terms and conditions</div>`,
+			want:     ErrConsentRequired,
+		},
+		{
+			name:     "required action redirect",
+			location: "https://example.com/login-actions/required-action?execution=TERMS",
+			want:     ErrConsentRequired,
+		},
+		{
+			name:     "consent required error",
+			location: "https://example.com/callback?error=consent_required&error_description=grant+consent",
+			want:     ErrConsentRequired,
+			message:  "grant consent",
+		},
+		{
+			name:     "other authorization error",
+			location: "https://example.com/callback?error=invalid_request&error_description=bad+request",
+			want:     ErrLoginFormChanged,
+			message:  "bad request",
+		},
+		{
+			name:     "message without login form",
+			location: "",
+			body:     `<span id="input-error">Something went wrong</span>`,
+			want:     ErrLoginFormChanged,
+			message:  "Something went wrong",
+		},
+		{
+			name:     "unknown page",
+			location: "",
+			body:     `<html></html>`,
+			want:     ErrLoginFormChanged,
+			message:  "could not get code",
+		},
+		{
+			name:     "escaped message",
+			location: "",
+			body:     `<span id="input-error">Benutzername oder Passwort ung&uuml;ltig</span>` + loginForm,
+			want:     ErrInvalidCredentials,
+			message:  "Benutzername oder Passwort ungültig",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			location, err := url.Parse(tc.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = parseLoginError(location, tc.body)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("parseLoginError() = %v, want %v", err, tc.want)
+			}
+			var loginErr *LoginError
+			if !errors.As(err, &loginErr) {
+				t.Fatalf("parseLoginError() = %T, want *LoginError", err)
+			}
+			if loginErr.Message != tc.message {
+				t.Errorf("message = %q, want %q", loginErr.Message, tc.message)
+			}
+		})
+	}
+}
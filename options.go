@@ -1,6 +1,12 @@
 package sensonet
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
 
 type ConnOption func(*Connection)
 
@@ -10,6 +16,37 @@ func WithHttpClient(client *http.Client) ConnOption {
 	}
 }
 
+// WithBaseURL sets the base url of the API, e.g. to use a proxy or a test server.
+// It takes precedence over the endpoints of WithEndpoints() and WithProfile(), regardless of the order of the options.
+func WithBaseURL(baseURL string) ConnOption {
+	return func(c *Connection) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithEndpoints sets the endpoints, e.g. the ones returned by EndpointsForBrand()
+func WithEndpoints(endpoints Endpoints) ConnOption {
+	return func(c *Connection) {
+		c.endpoints = endpoints
+	}
+}
+
+// WithProfile sets the brand profile. The endpoints of the profile are used as well.
+// Empty fields of the profile are taken from ProfileForBrand() for the brand of the profile.
+func WithProfile(profile Profile) ConnOption {
+	return func(c *Connection) {
+		c.profile = profile.withDefaults()
+		c.endpoints = c.profile.Endpoints
+	}
+}
+
+// WithConnectionLogger sets a logger for warnings of the connection, e.g. about an unknown control identifier
+func WithConnectionLogger(logger Logger) ConnOption {
+	return func(c *Connection) {
+		c.logger = logger
+	}
+}
+
 type CtrlOption func(*Controller)
 
 func WithLogger(logger Logger) CtrlOption {
@@ -17,3 +54,92 @@ func WithLogger(logger Logger) CtrlOption {
 		c.logger = logger
 	}
 }
+
+// The durations of the caches must not be shorter than CACHE_DURATION_MIN seconds. NewController returns ErrDurationTooShort otherwise.
+
+// WithHomesCacheDuration sets the duration of the homes cache (default CACHE_DURATION_HOMES seconds)
+func WithHomesCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.homes = d
+	}
+}
+
+// WithSystemsCacheDuration sets the duration of the systems cache (default CACHE_DURATION_SYSTEMS seconds)
+func WithSystemsCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.systems = d
+	}
+}
+
+// WithDevicesCacheDuration sets the duration of the system devices cache (default CACHE_DURATION_DEVICES seconds)
+func WithDevicesCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.devices = d
+	}
+}
+
+// WithMpcDataCacheDuration sets the duration of the mpc data cache (default CACHE_DURATION_MPCDATA seconds)
+func WithMpcDataCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.mpcData = d
+	}
+}
+
+// WithRoomsCacheDuration sets the duration of the ambisense rooms cache (default CACHE_DURATION_ROOMS seconds)
+func WithRoomsCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.rooms = d
+	}
+}
+
+// WithDiagnosticsCacheDuration sets the duration of the diagnostics cache (default CACHE_DURATION_DIAGNOSTICS seconds)
+func WithDiagnosticsCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.diagnostics = d
+	}
+}
+
+// WithLiveReportCacheDuration sets the duration of the live report cache (default CACHE_DURATION_LIVEREPORT seconds)
+func WithLiveReportCacheDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.liveReport = d
+	}
+}
+
+// WithQuickModeHysteresis sets the minimum time between a start or stop of a quick mode and a change of the quick mode
+// that is detected in the system state (default two times the systems cache duration). It must not be shorter than the
+// systems cache duration.
+func WithQuickModeHysteresis(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.quickModeHysteresis = d
+	}
+}
+
+// WithIdleModeDuration sets how long the idle mode is kept, if no quick mode was possible (default IDLEMODE_DURATION seconds)
+func WithIdleModeDuration(d time.Duration) CtrlOption {
+	return func(c *Controller) {
+		c.durations.idleMode = d
+	}
+}
+
+// WithServeStaleData keeps the last successful data of every cache of the controller. If an update fails, the getters
+// return this data together with a StaleError that contains the error and the age of the data. The setters use stale
+// data without error, as they only need the configuration of the system.
+// With backgroundRefresh, expired data are returned at once and updated in the background.
+func WithServeStaleData(backgroundRefresh bool) CtrlOption {
+	return func(c *Controller) {
+		if backgroundRefresh {
+			c.cacheOpts = append(c.cacheOpts, WithBackgroundRefresh())
+		} else {
+			c.cacheOpts = append(c.cacheOpts, WithServeStale())
+		}
+	}
+}
+
+// WithClock sets the clock of the controller and of its caches, e.g. clock.NewMock() for tests of the cache expiry,
+// the back-off and the quick mode timing
+func WithClock(clk clock.Clock) CtrlOption {
+	return func(c *Controller) {
+		c.clock = clk
+	}
+}
@@ -0,0 +1,102 @@
+package sensonet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTimeProgram is returned (wrapped) if a time program does not fit its MetaInfo
+var ErrInvalidTimeProgram = errors.New("invalid time program")
+
+const MINUTES_PER_DAY = 24 * 60
+
+var weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// Returns the slots of the time program per weekday ("monday", "tuesday", ...)
+func (tp TimeProgram) Days() map[string][]Setpoint {
+	return map[string][]Setpoint{
+		"monday":    tp.Monday,
+		"tuesday":   tp.Tuesday,
+		"wednesday": tp.Wednesday,
+		"thursday":  tp.Thursday,
+		"friday":    tp.Friday,
+		"saturday":  tp.Saturday,
+		"sunday":    tp.Sunday,
+	}
+}
+
+// Validate checks the time program against MinSlotsPerDay, MaxSlotsPerDay and SetpointRequiredPerSlot of its MetaInfo.
+// Start and end times are minutes after midnight. The slots of a day must be sorted and must not overlap.
+func (tp TimeProgram) Validate() error {
+	days := tp.Days()
+	for _, day := range weekdays {
+		slots := days[day]
+		if len(slots) < tp.MetaInfo.MinSlotsPerDay {
+			return fmt.Errorf("%w: %s has %d slots, minimum is %d", ErrInvalidTimeProgram, day, len(slots), tp.MetaInfo.MinSlotsPerDay)
+		}
+		if tp.MetaInfo.MaxSlotsPerDay > 0 && len(slots) > tp.MetaInfo.MaxSlotsPerDay {
+			return fmt.Errorf("%w: %s has %d slots, maximum is %d", ErrInvalidTimeProgram, day, len(slots), tp.MetaInfo.MaxSlotsPerDay)
+		}
+		lastEnd := 0
+		for i, slot := range slots {
+			if slot.StartTime < lastEnd || slot.StartTime >= slot.EndTime || slot.EndTime > MINUTES_PER_DAY {
+				return fmt.Errorf("%w: %s slot %d has invalid times %d-%d", ErrInvalidTimeProgram, day, i, slot.StartTime, slot.EndTime)
+			}
+			if tp.MetaInfo.SetpointRequiredPerSlot && slot.Setpoint <= 0 {
+				return fmt.Errorf("%w: %s slot %d has no setpoint", ErrInvalidTimeProgram, day, i)
+			}
+			lastEnd = slot.EndTime
+		}
+	}
+	return nil
+}
+
+// Returns the slots of the room time program per weekday ("monday", "tuesday", ...)
+func (tp RoomTimeProgram) Days() map[string][]RoomSetpoint {
+	return map[string][]RoomSetpoint{
+		"monday":    tp.Monday,
+		"tuesday":   tp.Tuesday,
+		"wednesday": tp.Wednesday,
+		"thursday":  tp.Thursday,
+		"friday":    tp.Friday,
+		"saturday":  tp.Saturday,
+		"sunday":    tp.Sunday,
+	}
+}
+
+// Validate checks that the slots of every day are sorted by start time, start within the day
+// and have a temperature setpoint between ROOMSETPOINT_MIN and ROOMSETPOINT_MAX.
+func (tp RoomTimeProgram) Validate() error {
+	days := tp.Days()
+	for _, day := range weekdays {
+		lastStart := -1
+		for i, slot := range days[day] {
+			if slot.StartTime <= lastStart || slot.StartTime >= MINUTES_PER_DAY {
+				return fmt.Errorf("%w: %s slot %d has invalid start time %d", ErrInvalidTimeProgram, day, i, slot.StartTime)
+			}
+			if slot.TemperatureSetpoint < ROOMSETPOINT_MIN || slot.TemperatureSetpoint > ROOMSETPOINT_MAX {
+				return fmt.Errorf("%w: %s slot %d has invalid setpoint %.1f", ErrInvalidTimeProgram, day, i, slot.TemperatureSetpoint)
+			}
+			lastStart = slot.StartTime
+		}
+	}
+	return nil
+}
+
+// timeProgramData returns the request data for the time program. Setpoints are only sent if they are required per slot.
+func timeProgramData(tp TimeProgram) map[string]any {
+	data := make(map[string]any)
+	for day, slots := range tp.Days() {
+		if tp.MetaInfo.SetpointRequiredPerSlot {
+			setpoints := make([]Setpoint, 0, len(slots))
+			data[day] = append(setpoints, slots...)
+			continue
+		}
+		timeSlots := make([]TimeSlot, 0, len(slots))
+		for _, slot := range slots {
+			timeSlots = append(timeSlots, TimeSlot{StartTime: slot.StartTime, EndTime: slot.EndTime})
+		}
+		data[day] = timeSlots
+	}
+	return data
+}
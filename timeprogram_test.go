@@ -0,0 +1,79 @@
+package sensonet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTimeProgramValidate(t *testing.T) {
+	slots := func(s ...Setpoint) []Setpoint { return s }
+	week := func(day []Setpoint, metaInfo MetaInfo) TimeProgram {
+		return TimeProgram{
+			MetaInfo: metaInfo,
+			Monday:   day, Tuesday: day, Wednesday: day, Thursday: day, Friday: day, Saturday: day, Sunday: day,
+		}
+	}
+	metaInfo := MetaInfo{MinSlotsPerDay: 0, MaxSlotsPerDay: 3}
+
+	tests := []struct {
+		name    string
+		tp      TimeProgram
+		wantErr bool
+	}{
+		{"valid", week(slots(Setpoint{StartTime: 360, EndTime: 480}, Setpoint{StartTime: 1020, EndTime: 1320}), metaInfo), false},
+		{"empty days", week(nil, metaInfo), false},
+		{"whole day", week(slots(Setpoint{StartTime: 0, EndTime: MINUTES_PER_DAY}), metaInfo), false},
+		{"too few slots", week(nil, MetaInfo{MinSlotsPerDay: 1, MaxSlotsPerDay: 3}), true},
+		{"too many slots", week(slots(
+			Setpoint{StartTime: 0, EndTime: 60}, Setpoint{StartTime: 60, EndTime: 120},
+			Setpoint{StartTime: 120, EndTime: 180}, Setpoint{StartTime: 180, EndTime: 240},
+		), metaInfo), true},
+		{"no maximum", week(slots(
+			Setpoint{StartTime: 0, EndTime: 60}, Setpoint{StartTime: 60, EndTime: 120},
+			Setpoint{StartTime: 120, EndTime: 180}, Setpoint{StartTime: 180, EndTime: 240},
+		), MetaInfo{}), false},
+		{"end before start", week(slots(Setpoint{StartTime: 480, EndTime: 360}), metaInfo), true},
+		{"empty slot", week(slots(Setpoint{StartTime: 480, EndTime: 480}), metaInfo), true},
+		{"end after midnight", week(slots(Setpoint{StartTime: 1380, EndTime: MINUTES_PER_DAY + 1}), metaInfo), true},
+		{"overlapping", week(slots(Setpoint{StartTime: 360, EndTime: 600}, Setpoint{StartTime: 540, EndTime: 720}), metaInfo), true},
+		{"unsorted", week(slots(Setpoint{StartTime: 1020, EndTime: 1320}, Setpoint{StartTime: 360, EndTime: 480}), metaInfo), true},
+		{"missing setpoint", week(slots(Setpoint{StartTime: 360, EndTime: 480}), MetaInfo{MaxSlotsPerDay: 3, SetpointRequiredPerSlot: true}), true},
+		{"required setpoint", week(slots(Setpoint{StartTime: 360, EndTime: 480, Setpoint: 21}), MetaInfo{MaxSlotsPerDay: 3, SetpointRequiredPerSlot: true}), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.tp.Validate()
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("Validate() = %v, want error: %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTimeProgram) {
+				t.Errorf("Validate() = %v, want ErrInvalidTimeProgram", err)
+			}
+		})
+	}
+
+	// a single invalid day makes the whole program invalid
+	tp := week(slots(Setpoint{StartTime: 360, EndTime: 480}), metaInfo)
+	tp.Sunday = slots(Setpoint{StartTime: 480, EndTime: 360})
+	if err := tp.Validate(); !errors.Is(err, ErrInvalidTimeProgram) {
+		t.Errorf("Validate() with invalid sunday = %v, want ErrInvalidTimeProgram", err)
+	}
+}
+
+func TestTimeProgramData(t *testing.T) {
+	tp := TimeProgram{Monday: []Setpoint{{StartTime: 360, EndTime: 480, Setpoint: 21}}}
+
+	data := timeProgramData(tp)
+	if slots, ok := data["monday"].([]TimeSlot); !ok || len(slots) != 1 || slots[0] != (TimeSlot{StartTime: 360, EndTime: 480}) {
+		t.Errorf("monday = %#v, want time slots without setpoint", data["monday"])
+	}
+
+	tp.MetaInfo.SetpointRequiredPerSlot = true
+	data = timeProgramData(tp)
+	if slots, ok := data["monday"].([]Setpoint); !ok || len(slots) != 1 || slots[0].Setpoint != 21 {
+		t.Errorf("monday = %#v, want setpoints", data["monday"])
+	}
+	if len(data) != len(weekdays) {
+		t.Errorf("got %d days, want %d", len(data), len(weekdays))
+	}
+}
@@ -0,0 +1,228 @@
+package sensonet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoToken is returned by a TokenStore if no token is stored
+var ErrNoToken = errors.New("no token stored")
+
+// TokenStore is the interface for loading and saving oauth2 tokens
+type TokenStore interface {
+	Load() (*oauth2.Token, error)
+	Save(token *oauth2.Token) error
+}
+
+// FileTokenStore stores the token as json file
+type FileTokenStore struct {
+	filename string
+}
+
+var _ TokenStore = (*FileTokenStore)(nil)
+
+func NewFileTokenStore(filename string) *FileTokenStore {
+	return &FileTokenStore{filename: filename}
+}
+
+func (s *FileTokenStore) Load() (*oauth2.Token, error) {
+	b, err := os.ReadFile(s.filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *FileTokenStore) Save(token *oauth2.Token) error {
+	b, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filename, b, 0o600)
+}
+
+// MemoryTokenStore keeps the token in memory only
+type MemoryTokenStore struct {
+	mux   sync.Mutex
+	token *oauth2.Token
+}
+
+var _ TokenStore = (*MemoryTokenStore)(nil)
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (s *MemoryTokenStore) Load() (*oauth2.Token, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.token == nil {
+		return nil, ErrNoToken
+	}
+	token := *s.token
+	return &token, nil
+}
+
+func (s *MemoryTokenStore) Save(token *oauth2.Token) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	t := *token
+	s.token = &t
+	return nil
+}
+
+// Keyring is the interface of a secret store like the keyring of the operating system.
+// It matches e.g. the functions of github.com/zalando/go-keyring.
+type Keyring interface {
+	Get(service, user string) (string, error)
+	Set(service, user, secret string) error
+}
+
+// KeyringTokenStore stores the token as json string in a Keyring
+type KeyringTokenStore struct {
+	keyring      Keyring
+	service      string
+	user         string
+	notFoundErrs []error
+}
+
+var _ TokenStore = (*KeyringTokenStore)(nil)
+
+// NewKeyringTokenStore creates a token store for service and user of keyring. notFoundErrs are the errors of the keyring
+// for a missing secret (e.g. keyring.ErrNotFound of github.com/zalando/go-keyring). They are returned as ErrNoToken.
+func NewKeyringTokenStore(keyring Keyring, service, user string, notFoundErrs ...error) *KeyringTokenStore {
+	return &KeyringTokenStore{
+		keyring:      keyring,
+		service:      service,
+		user:         user,
+		notFoundErrs: notFoundErrs,
+	}
+}
+
+func (s *KeyringTokenStore) Load() (*oauth2.Token, error) {
+	secret, err := s.keyring.Get(s.service, s.user)
+	for _, notFoundErr := range s.notFoundErrs {
+		if errors.Is(err, notFoundErr) {
+			return nil, ErrNoToken
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, ErrNoToken
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal([]byte(secret), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *KeyringTokenStore) Save(token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return s.keyring.Set(s.service, s.user, string(b))
+}
+
+// storedTokenSource is a token source that saves every new token in a TokenStore
+type storedTokenSource struct {
+	mux      sync.Mutex
+	ctx      context.Context
+	oc       *Oauth2Config
+	store    TokenStore
+	username string
+	password string
+	ts       oauth2.TokenSource
+	last     *oauth2.Token
+}
+
+// StoredTokenSource returns a token source that starts with the token from store and saves every refreshed token in store.
+// If no token is stored or if the refresh token has expired, a new token is requested by PasswordCredentialsToken()
+// using username and password. If username is empty, no password login is done.
+func (oc *Oauth2Config) StoredTokenSource(ctx context.Context, store TokenStore, username, password string) oauth2.TokenSource {
+	return &storedTokenSource{
+		ctx:      ctx,
+		oc:       oc,
+		store:    store,
+		username: username,
+		password: password,
+	}
+}
+
+func (s *storedTokenSource) Token() (*oauth2.Token, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.ts == nil {
+		// only a missing token leads to a password login, other errors (e.g. a corrupt file or a locked keyring)
+		// are returned, so that the stored token is not overwritten
+		token, err := s.store.Load()
+		if err != nil && !errors.Is(err, ErrNoToken) {
+			return nil, err
+		}
+		if err != nil || token == nil {
+			if token, err = s.login(err); err != nil {
+				return nil, err
+			}
+		}
+		s.last = token
+		s.ts = s.oc.TokenSource(s.ctx, token)
+	}
+
+	token, err := s.ts.Token()
+	if err != nil {
+		// only a rejected refresh token requires a new login. Other errors of the identity provider
+		// (e.g. 429 or 5xx) are returned, so that they do not lead to repeated password logins.
+		var retrieveErr *oauth2.RetrieveError
+		if !errors.As(err, &retrieveErr) || retrieveErr.ErrorCode != "invalid_grant" {
+			return nil, err
+		}
+		if token, err = s.login(err); err != nil {
+			return nil, err
+		}
+		s.ts = s.oc.TokenSource(s.ctx, token)
+	}
+
+	if s.last == nil || token.AccessToken != s.last.AccessToken {
+		if err := s.store.Save(token); err != nil {
+			return nil, err
+		}
+		s.last = token
+	}
+
+	return token, nil
+}
+
+// login requests a new token by password. cause is returned if no credentials are available.
+func (s *storedTokenSource) login(cause error) (*oauth2.Token, error) {
+	if s.username == "" {
+		if cause == nil {
+			cause = ErrNoToken
+		}
+		return nil, cause
+	}
+	token, err := s.oc.PasswordCredentialsToken(s.ctx, s.username, s.password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Save(token); err != nil {
+		return nil, err
+	}
+	s.last = token
+	return token, nil
+}
@@ -0,0 +1,234 @@
+package sensonet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var errKeyringNotFound = errors.New("secret not found in keyring")
+
+type testKeyring struct {
+	secrets map[string]string
+	err     error
+}
+
+func (k *testKeyring) Get(service, user string) (string, error) {
+	if k.err != nil {
+		return "", k.err
+	}
+	secret, ok := k.secrets[service+"/"+user]
+	if !ok {
+		return "", errKeyringNotFound
+	}
+	return secret, nil
+}
+
+func (k *testKeyring) Set(service, user, secret string) error {
+	if k.err != nil {
+		return k.err
+	}
+	k.secrets[service+"/"+user] = secret
+	return nil
+}
+
+func TestTokenStores(t *testing.T) {
+	token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour).Round(time.Second)}
+
+	for name, store := range map[string]TokenStore{
+		"file":    NewFileTokenStore(filepath.Join(t.TempDir(), "token.json")),
+		"memory":  NewMemoryTokenStore(),
+		"keyring": NewKeyringTokenStore(&testKeyring{secrets: map[string]string{}}, "sensonet", "user", errKeyringNotFound),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(); !errors.Is(err, ErrNoToken) {
+				t.Fatalf("Load() of empty store: got %v, want ErrNoToken", err)
+			}
+			if err := store.Save(token); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.AccessToken != token.AccessToken || loaded.RefreshToken != token.RefreshToken || !loaded.Expiry.Equal(token.Expiry) {
+				t.Errorf("Load() = %+v, want %+v", loaded, token)
+			}
+		})
+	}
+}
+
+func TestKeyringTokenStoreErrors(t *testing.T) {
+	errLocked := errors.New("keyring locked")
+
+	tests := []struct {
+		name    string
+		keyring *testKeyring
+		want    error
+	}{
+		{"not found", &testKeyring{secrets: map[string]string{}}, ErrNoToken},
+		{"locked", &testKeyring{err: errLocked}, errLocked},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewKeyringTokenStore(tc.keyring, "sensonet", "user", errKeyringNotFound)
+			if _, err := store.Load(); !errors.Is(err, tc.want) {
+				t.Errorf("Load() = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoredTokenSourceLoadErrors(t *testing.T) {
+	// every request to the identity provider means that a password login was attempted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	profile := ProfileForBrand(BRAND_VAILLANT, "")
+	profile.Endpoints.AuthURLBase = srv.URL
+	oc := Oauth2ConfigForProfile(profile)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, srv.Client())
+
+	t.Run("corrupt file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "token.json")
+		if err := os.WriteFile(filename, []byte("{corrupt"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		ts := oc.StoredTokenSource(ctx, NewFileTokenStore(filename), "user", "password")
+		if _, err := ts.Token(); err == nil || errors.Is(err, ErrNoToken) {
+			t.Errorf("Token() = %v, want the decoding error", err)
+		}
+		if b, _ := os.ReadFile(filename); string(b) != "{corrupt" {
+			t.Errorf("token file was overwritten: %s", b)
+		}
+	})
+
+	t.Run("locked keyring", func(t *testing.T) {
+		errLocked := errors.New("keyring locked")
+		store := NewKeyringTokenStore(&testKeyring{err: errLocked}, "sensonet", "user", errKeyringNotFound)
+		ts := oc.StoredTokenSource(ctx, store, "user", "password")
+		if _, err := ts.Token(); !errors.Is(err, errLocked) {
+			t.Errorf("Token() = %v, want %v", err, errLocked)
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		store := NewMemoryTokenStore()
+		token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+		_ = store.Save(token)
+		ts := oc.StoredTokenSource(ctx, store, "user", "password")
+		got, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AccessToken != token.AccessToken {
+			t.Errorf("Token() = %s, want %s", got.AccessToken, token.AccessToken)
+		}
+	})
+
+	t.Run("no token without credentials", func(t *testing.T) {
+		ts := oc.StoredTokenSource(ctx, NewMemoryTokenStore(), "", "")
+		if _, err := ts.Token(); !errors.Is(err, ErrNoToken) {
+			t.Errorf("Token() = %v, want ErrNoToken", err)
+		}
+	})
+}
+
+func TestStoredTokenSourceRefresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantToken  string
+		wantLogins int
+	}{
+		{
+			name:      "refreshed token",
+			status:    http.StatusOK,
+			body:      `{"access_token":"new-access","refresh_token":"new-refresh","token_type":"Bearer","expires_in":3600}`,
+			wantToken: "new-access",
+		},
+		{
+			name:       "rejected refresh token",
+			status:     http.StatusBadRequest,
+			body:       `{"error":"invalid_grant","error_description":"Token is not active"}`,
+			wantLogins: 1,
+		},
+		{
+			name:   "server error",
+			status: http.StatusServiceUnavailable,
+			body:   `{"error":"temporarily_unavailable"}`,
+		},
+		{
+			name:   "rate limited",
+			status: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logins int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != fmt.Sprintf(TOKEN_PATH, REALM_GERMANY) {
+					// the login page without form fails the password login
+					logins++
+					return
+				}
+				if err := r.ParseForm(); err != nil || r.Form.Get("refresh_token") != "refresh" {
+					t.Errorf("unexpected token request %v", r.Form)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			profile := ProfileForBrand(BRAND_VAILLANT, "")
+			profile.Endpoints.AuthURLBase = srv.URL
+			oc := Oauth2ConfigForProfile(profile)
+			ctx := context.WithValue(context.Background(), oauth2.HTTPClient, srv.Client())
+
+			store := NewMemoryTokenStore()
+			_ = store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)})
+
+			token, err := oc.StoredTokenSource(ctx, store, "user", "password").Token()
+			if logins != tc.wantLogins {
+				t.Errorf("logins: got %d, want %d", logins, tc.wantLogins)
+			}
+			stored, _ := store.Load()
+
+			if tc.wantToken == "" {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if stored.AccessToken != "access" {
+					t.Errorf("stored token was overwritten: %s", stored.AccessToken)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != tc.wantToken {
+				t.Errorf("Token() = %s, want %s", token.AccessToken, tc.wantToken)
+			}
+			// the refreshed token is saved back to the store
+			if stored.AccessToken != tc.wantToken || stored.RefreshToken != "new-refresh" {
+				t.Errorf("stored token: got %s/%s, want %s/new-refresh", stored.AccessToken, stored.RefreshToken, tc.wantToken)
+			}
+		})
+	}
+}
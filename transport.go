@@ -4,26 +4,36 @@ import "net/http"
 
 type transport struct {
 	http.RoundTripper
+	profile Profile
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	for k, v := range (http.Header{
-		"Accept-Language":           {"en-GB"},
+		"Accept-Language":           {t.profile.Locale},
 		"Accept":                    {"application/json, text/plain, */*"},
-		"x-app-identifier":          {"VAILLANT"},
-		"x-client-locale":           {"en-GB"},
+		"x-app-identifier":          {t.profile.AppIdentifier},
+		"x-client-locale":           {t.profile.Locale},
 		"x-idm-identifier":          {"KEYCLOAK"},
-		"ocp-apim-subscription-key": {"1e0a2f3511fb4c5bbb1c7f9fedd20b1c"},
+		"ocp-apim-subscription-key": {t.profile.SubscriptionKey},
 	}) {
 		for _, vv := range v {
 			req.Header.Add(k, vv)
 		}
 	}
 
-	resp, err := t.RoundTripper.RoundTrip(req)
-	if err == nil {
-		err = ResponseError(resp)
+	rt := t.RoundTripper
+	if rt == nil {
+		rt = http.DefaultTransport
 	}
 
-	return resp, err
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// the http client drops the response if an error is returned, so the body is kept in the APIError
+	if err := ResponseError(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
 }
@@ -1,41 +1,159 @@
 package sensonet
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
 const (
-	CLIENT_ID    = "myvaillant"
-	REDIRECT_URL = "enduservaillant.page.link://login"
-
-	AUTH_BASE_URL     = "https://identity.vaillant-group.com/auth/realms"
-	LOGIN_URL         = AUTH_BASE_URL + "/%s/login-actions/authenticate"
-	TOKEN_URL         = AUTH_BASE_URL + "/%s/protocol/openid-connect/token"
-	AUTH_URL          = AUTH_BASE_URL + "/%s/protocol/openid-connect/auth"
-	API_URL_BASE      = "https://api.vaillant-group.com/service-connected-control/end-user-app-api/v1"
+	CLIENT_ID        = "myvaillant"
+	REDIRECT_URL     = "enduservaillant.page.link://login"
+	LOCALE_DEFAULT   = "en-GB"
+	SUBSCRIPTION_KEY = "1e0a2f3511fb4c5bbb1c7f9fedd20b1c"
+
+	AUTH_BASE_URL                  = "https://identity.vaillant-group.com/auth/realms"
+	LOGIN_PATH                     = "/%s/login-actions/authenticate"
+	TOKEN_PATH                     = "/%s/protocol/openid-connect/token"
+	AUTH_PATH                      = "/%s/protocol/openid-connect/auth"
+	LOGIN_URL                      = AUTH_BASE_URL + LOGIN_PATH
+	TOKEN_URL                      = AUTH_BASE_URL + TOKEN_PATH
+	AUTH_URL                       = AUTH_BASE_URL + AUTH_PATH
+	API_URL_BASE                   = "https://api.vaillant-group.com/service-connected-control/end-user-app-api/v1"
+	HOTWATEROPERATIONMODE_URL      = "/domestic-hot-water/%01d/operation-mode"
+	HOTWATERSETPOINT_URL           = "/domestic-hot-water/%01d/temperature"
+	HOTWATERHOLIDAY_URL            = "/domestic-hot-water/%01d/holiday"
+	HOTWATERTIMEPROGRAM_URL        = "/domestic-hot-water/%01d/time-windows"
+	CIRCULATIONPUMPTIMEPROGRAM_URL = "/domestic-hot-water/%01d/circulation-pump-time-windows"
+	ZONETIMEPROGRAM_URL            = "/zones/%01d/time-windows"
+	ZONEOPERATIONMODE_URL          = "/zones/%01d/heating-operation-mode"
+	ZONECOOLINGOPERATIONMODE_URL   = "/zones/%01d/cooling-operation-mode"
+	ZONEHOLIDAY_URL                = "/zones/%01d/holiday"
+	ZONEMANUALMODESETPOINT_URL     = "/zones/%01d/manual-mode-setpoint"
+	ZONESETBACKTEMPERATURE_URL     = "/zones/%01d/set-back-temperature"
+	CIRCUITHEATINGCURVE_URL        = "/circuits/%01d/heating-curve"
+	CIRCUITMINFLOWTEMPERATURE_URL  = "/circuits/%01d/min-flow-temperature-setpoint"
+	CIRCUITMAXFLOWTEMPERATURE_URL  = "/circuits/%01d/max-flow-temperature-setpoint"
+	CIRCUITHEATDEMANDLIMIT_URL     = "/circuits/%01d/heat-demand-limited-by-outside-temperature"
+	VENTILATIONOPERATIONMODE_URL   = "/ventilations/%01d/operation-mode"
+	VENTILATIONFANSTAGE_URL        = "/ventilations/%01d/fan-stage"
+	VENTILATIONTIMEPROGRAM_URL     = "/ventilations/%01d/time-windows"
+	CONTROLIDENTIFIER_URL          = "/systems/%s/meta-info/control-identifier"
+	DEVICES_URL                    = "/emf/v2/%s/currentSystem"
+	ENERGY_URL                     = "/emf/v2/%s/devices/%s/buckets?"
+	MPC_URL                        = "/hem/%s/mpc"
+	ROOMS_URL                      = "/api/v1/ambisense/facilities/%s/rooms"
+	ROOMTIMEPROGRAM_URL            = "/api/v1/ambisense/facilities/%s/rooms/%01d/timeprogram"
+	ROOMQUICKVETO_URL              = "/api/v1/ambisense/facilities/%s/rooms/%01d/configuration/quick-veto"
+	DIAGNOSTICS_URL                = "/systems/%s/diagnostic-trouble-codes"
+	NOTIFICATIONS_URL              = "/systems/%s/notifications"
+	LIVEREPORT_URL                 = "/rts/%s/live-report"
+)
+
+// URLs of TLI systems, relative to API_URL_BASE
+const (
+	// Deprecated: only valid for TLI systems. The Connection routes system requests by the control identifier of the system.
 	HOTWATERBOOST_URL = "/systems/%s/tli/domestic-hot-water/%01d/boost"
+	// Deprecated: only valid for TLI systems. The Connection routes system requests by the control identifier of the system.
 	ZONEQUICKVETO_URL = "/systems/%s/tli/zones/%01d/quick-veto"
-	SYSTEMS_URL       = "/systems/%s/tli"
-	DEVICES_URL       = "/emf/v2/%s/currentSystem"
-	ENERGY_URL        = "/emf/v2/%s/devices/%s/buckets?"
-	MPC_URL           = "/hem/%s/mpc"
+	// Deprecated: only valid for TLI systems. The Connection routes system requests by the control identifier of the system.
+	SYSTEMS_URL = "/systems/%s/tli"
+)
+
+// Paths of the system api. systemPath is formatted with systemId and control identifier, the other paths are appended to it.
+const (
+	systemPath        = "/systems/%s/%s"
+	hotWaterBoostPath = "/domestic-hot-water/%01d/boost"
+	zoneQuickVetoPath = "/zones/%01d/quick-veto"
 )
 
 const (
-	HOTWATERINDEX_DEFAULT                = 255
-	ZONEINDEX_DEFAULT                    = 0
-	ZONEVETOSETPOINT_DEFAULT             = 20.0
-	ZONEVETODURATION_DEFAULT             = 3.0 // 3 hours as default
-	OPERATIONMODE_TIME_CONTROLLED string = "TIME_CONTROLLED"
-	QUICKMODE_HOTWATER            string = "Hotwater Boost"
-	QUICKMODE_HEATING             string = "Heating Quick Veto"
-	QUICKMODE_NOTHING             string = "Charger running idle"
-	QUICKMODE_ERROR_ALREADYON     string = "Error. A quickmode is already running"
+	HOTWATERINDEX_DEFAULT            = 255
+	ZONEINDEX_DEFAULT                = 0
+	ZONEVETOSETPOINT_DEFAULT         = 20.0
+	ZONEVETODURATION_DEFAULT         = 3.0 // 3 hours as default
+	ZONESETPOINT_MIN                 = 5.0
+	ZONESETPOINT_MAX                 = 30.0
+	ROOMSETPOINT_MIN                 = 5.0
+	ROOMSETPOINT_MAX                 = 30.0
+	HOTWATERSETPOINT_MIN             = 35.0 // used if the properties of the hot water contain no range
+	HOTWATERSETPOINT_MAX             = 70.0
+	CIRCUITINDEX_DEFAULT             = 0
+	HEATINGCURVE_MIN                 = 0.1
+	HEATINGCURVE_MAX                 = 4.0
+	FLOWTEMPERATURE_MIN              = 15.0
+	FLOWTEMPERATURE_MAX              = 80.0
+	HEATDEMANDLIMIT_MIN              = 10.0
+	HEATDEMANDLIMIT_MAX              = 99.0
+	VENTILATIONINDEX_DEFAULT         = 0
+	FANSTAGE_MIN                     = 1
+	FANSTAGE_MAX                     = 6
+	QUICKMODE_HOTWATER        string = "Hotwater Boost"
+	QUICKMODE_HEATING         string = "Heating Quick Veto"
+	QUICKMODE_NOTHING         string = "Charger running idle"
+	QUICKMODE_ERROR_ALREADYON string = "Error. A quickmode is already running"
 
 	SPECIAL_FUNCTION_QUICK_VETO     = "QUICK_VETO"
 	SPECIAL_FUNCTION_HOTWATER_BOOST = "CYLINDER_BOOST"
+
+	TIMEPROGRAM_TYPE_HEATING = "heating"
+	TIMEPROGRAM_TYPE_COOLING = "cooling"
+	SETPOINT_TYPE_HEATING    = "HEATING"
+	SETPOINT_TYPE_COOLING    = "COOLING"
+	CIRCUITSTATE_COOLING     = "COOLING"
+	FANSTAGE_TYPE_DAY        = "DAY"
+	FANSTAGE_TYPE_NIGHT      = "NIGHT"
+)
+
+// Control identifiers of the system controls. They are part of the system urls.
+const (
+	CONTROL_IDENTIFIER_TLI    = "tli"
+	CONTROL_IDENTIFIER_VRC700 = "vrc700"
 )
 
+// OperationMode is the operation mode of a zone, of the domestic hot water or of a ventilation
+// that is passed to the setters. The decoded configurations keep the operation modes as string.
+type OperationMode string
+
+const (
+	OPERATIONMODE_OFF             = "OFF"
+	OPERATIONMODE_MANUAL          = "MANUAL"
+	OPERATIONMODE_TIME_CONTROLLED = "TIME_CONTROLLED"
+	// Operation modes of zones and domestic hot water of VRC700 systems
+	OPERATIONMODE_DAY      = "DAY"
+	OPERATIONMODE_AUTO     = "AUTO"
+	OPERATIONMODE_SET_BACK = "SET_BACK"
+	// Operation modes of ventilations
+	OPERATIONMODE_NORMAL  = "NORMAL"
+	OPERATIONMODE_REDUCED = "REDUCED"
+)
+
+// ErrInvalidOperationMode is returned (wrapped) if an operation mode is not supported
+var ErrInvalidOperationMode = errors.New("invalid operation mode")
+
+// ErrCoolingNotAllowed is returned if cooling is requested for a zone that does not allow cooling
+var ErrCoolingNotAllowed = errors.New("cooling not allowed")
+
+// OutOfRangeError is returned if a value that should be sent to the API is outside of the allowed range
+type OutOfRangeError struct {
+	Name  string
+	Value float64
+	Min   float64
+	Max   float64
+}
+
+func (e *OutOfRangeError) Error() string {
+	return fmt.Sprintf("%s %.1f is out of range (%.1f to %.1f)", e.Name, e.Value, e.Min, e.Max)
+}
+
+// checkRange returns an OutOfRangeError if value is not between min and max
+func checkRange(name string, value, min, max float64) error {
+	if value < min || value > max {
+		return &OutOfRangeError{Name: name, Value: value, Min: min, Max: max}
+	}
+	return nil
+}
+
 const (
 	STRATEGY_NONE                  = 0
 	STRATEGY_HOTWATER              = 1
@@ -48,6 +166,9 @@ const (
 	DEVICES_PRIMARY_HEATER   = 1
 	DEVICES_SECONDARY_HEATER = 2
 	DEVICES_BACKUP_HEATER    = 3
+	DEVICES_SOLAR_STATION    = 4
+	DEVICES_VENTILATION      = 5
+	DEVICES_GATEWAY          = 6
 )
 
 const (
@@ -76,6 +197,15 @@ type HotwaterParStruct struct {
 	Index int
 }
 
+// HolidayStatus reports the active holiday or, if no holiday is active, the next planned holiday
+type HolidayStatus struct {
+	Active   bool
+	Planned  bool
+	Start    time.Time
+	End      time.Time
+	Setpoint float64
+}
+
 type Homes []struct {
 	HomeName string `json:"homeName"`
 	Address  struct {
@@ -123,7 +253,7 @@ type SystemStatus struct {
 		Circuits         []StateCircuit          `json:"circuits"`
 		Dhw              []StateDhw              `json:"dhw"`
 		DomesticHotWater []StateDomesticHotWater `json:"domesticHotWater"`
-		// Ventilations
+		Ventilations     []StateVentilation      `json:"ventilations"`
 	} `json:"state"`
 	Properties struct {
 		System struct {
@@ -140,7 +270,7 @@ type SystemStatus struct {
 		Circuits         []PropertiesCircuit          `json:"circuits"`
 		Dhw              []PropertiesDhw              `json:"dhw"`
 		DomesticHotWater []PropertiesDomesticHotWater `json:"domesticHotWater"`
-		// Ventilations
+		Ventilations     []PropertiesVentilation      `json:"ventilations"`
 	} `json:"properties"`
 	Configuration struct {
 		System struct {
@@ -163,7 +293,7 @@ type SystemStatus struct {
 		Circuits         []ConfigurationCircuit          `json:"circuits"`
 		Dhw              []ConfigurationDhw              `json:"dhw"`
 		DomesticHotWater []ConfigurationDomesticHotWater `json:"domesticHotWater"`
-		// Ventilations
+		Ventilations     []ConfigurationVentilation      `json:"ventilations"`
 	} `json:"configuration"`
 }
 
@@ -179,6 +309,18 @@ type DomesticHotWaterData struct {
 	Configuration ConfigurationDomesticHotWater
 }
 
+type CircuitData struct {
+	State         StateCircuit
+	Properties    PropertiesCircuit
+	Configuration ConfigurationCircuit
+}
+
+type VentilationData struct {
+	State         StateVentilation
+	Properties    PropertiesVentilation
+	Configuration ConfigurationVentilation
+}
+
 type ZoneData struct {
 	State         StateZone
 	Properties    PropertiesZone
@@ -202,6 +344,8 @@ type StateZone struct {
 	CurrentRoomHumidity                   float64 `json:"currentRoomHumidity,omitempty"`
 	CurrentSpecialFunction                string  `json:"currentSpecialFunction"`
 	HeatingState                          string  `json:"heatingState"`
+	DesiredRoomTemperatureSetpointCooling float64 `json:"desiredRoomTemperatureSetpointCooling,omitempty"`
+	CoolingState                          string  `json:"coolingState,omitempty"`
 }
 
 type StateCircuit struct {
@@ -224,6 +368,12 @@ type StateDomesticHotWater struct {
 	CurrentDomesticHotWaterTemperature float64 `json:"currentDomesticHotWaterTemperature"`
 }
 
+type StateVentilation struct {
+	Index            int    `json:"index"`
+	VentilationState string `json:"ventilationState"`
+	CurrentFanStage  int    `json:"currentFanStage,omitempty"`
+}
+
 type PropertiesZone struct {
 	Index                  int    `json:"index"`
 	IsActive               bool   `json:"isActive"`
@@ -250,6 +400,11 @@ type PropertiesDomesticHotWater struct {
 	MaxSetpoint float64 `json:"maxSetpoint"`
 }
 
+type PropertiesVentilation struct {
+	Index           int `json:"index"`
+	MaximumFanStage int `json:"maximumFanStage"`
+}
+
 type TimeSlot struct {
 	StartTime int `json:"startTime"`
 	EndTime   int `json:"endTime"`
@@ -292,6 +447,11 @@ type ConfigurationZone struct {
 		ManualModeSetpointHeating float64     `json:"manualModeSetpointHeating"`
 		TimeProgramHeating        TimeProgram `json:"timeProgramHeating"`
 	} `json:"heating"`
+	Cooling struct {
+		OperationModeCooling string      `json:"operationModeCooling"`
+		SetpointCooling      float64     `json:"setpointCooling"`
+		TimeProgramCooling   TimeProgram `json:"timeProgramCooling"`
+	} `json:"cooling"`
 }
 
 type ConfigurationCircuit struct {
@@ -325,6 +485,14 @@ type ConfigurationDomesticHotWater struct {
 	TimeProgramCirculationPump    TimeProgram `json:"timeProgramCirculationPump"`
 }
 
+type ConfigurationVentilation struct {
+	Index                    int         `json:"index"`
+	OperationModeVentilation string      `json:"operationModeVentilation"`
+	MaximumDayFanStage       int         `json:"maximumDayFanStage"`
+	MaximumNightFanStage     int         `json:"maximumNightFanStage"`
+	TimeProgramVentilation   TimeProgram `json:"timeProgramVentilation"`
+}
+
 type EnergyData struct {
 	ExtraFields struct {
 		Timezone string `json:"timezone"`
@@ -377,9 +545,21 @@ type SystemDevices struct {
 	PrimaryHeatGenerator    Device   `json:"primary_heat_generator"`
 	SecondaryHeatGenerators []Device `json:"secondary_heat_generators"`
 	ElectricBackupHeater    Device   `json:"electric_backup_heater"`
-	SolarStation            any      `json:"solar_station"`
-	Ventilation             any      `json:"ventilation"`
-	Gateway                 any      `json:"gateway"`
+	SolarStation            Device   `json:"solar_station"`
+	Ventilation             Device   `json:"ventilation"`
+	Gateway                 Gateway  `json:"gateway"`
+}
+
+// Gateway is the internet gateway of the system (e.g. VR921)
+type Gateway struct {
+	Device
+	FirmwareVersion string `json:"firmware_version"`
+	OnlineState     string `json:"online_state"`
+}
+
+// isPresent returns true if the system devices contain any data of the gateway
+func (g Gateway) isPresent() bool {
+	return g.DeviceUUID != "" || g.DeviceSerialNumber != "" || g.FirmwareVersion != "" || g.OnlineState != ""
 }
 
 type DeviceAndInfo struct {
@@ -414,6 +594,116 @@ type AllSystemMpcData struct {
 	SystemMpcData []SystemMpcData
 }
 
+// Room is a room of a system with ambisense radiator thermostats
+type Room struct {
+	RoomIndex         int               `json:"roomIndex"`
+	RoomConfiguration RoomConfiguration `json:"roomConfiguration"`
+	TimeProgram       RoomTimeProgram   `json:"timeProgram"`
+}
+
+type RoomConfiguration struct {
+	Name                     string    `json:"name"`
+	IconID                   string    `json:"iconId"`
+	OperationMode            string    `json:"operationMode"`
+	TemperatureSetpoint      float64   `json:"temperatureSetpoint"`
+	CurrentTemperature       float64   `json:"currentTemperature"`
+	CurrentHumidity          float64   `json:"currentHumidity"`
+	QuickVetoStartTime       time.Time `json:"quickVetoStartTime"`
+	QuickVetoEndTime         time.Time `json:"quickVetoEndTime"`
+	QuickVetoTemperature     float64   `json:"quickVetoTemperature"`
+	IsWindowOpen             bool      `json:"isWindowOpen"`
+	IsRadioSignalStrengthLow bool      `json:"isRadioSignalStrengthLow"`
+	IsBatteryLow             bool      `json:"isBatteryLow"`
+}
+
+// RoomSetpoint is a slot of a room time program. It lasts until the start of the next slot.
+type RoomSetpoint struct {
+	StartTime           int     `json:"startTime"`
+	TemperatureSetpoint float64 `json:"temperatureSetpoint"`
+}
+
+type RoomTimeProgram struct {
+	Monday    []RoomSetpoint `json:"monday"`
+	Tuesday   []RoomSetpoint `json:"tuesday"`
+	Wednesday []RoomSetpoint `json:"wednesday"`
+	Thursday  []RoomSetpoint `json:"thursday"`
+	Friday    []RoomSetpoint `json:"friday"`
+	Saturday  []RoomSetpoint `json:"saturday"`
+	Sunday    []RoomSetpoint `json:"sunday"`
+}
+
+// DiagnosticType is the kind of a Diagnostic
+type DiagnosticType string
+
+const (
+	DIAGNOSTIC_TYPE_ERROR        DiagnosticType = "ERROR"
+	DIAGNOSTIC_TYPE_STATUS       DiagnosticType = "STATUS"
+	DIAGNOSTIC_TYPE_MAINTENANCE  DiagnosticType = "MAINTENANCE"
+	DIAGNOSTIC_TYPE_NOTIFICATION DiagnosticType = "NOTIFICATION"
+)
+
+// Diagnostic is an active status, error or maintenance code of a device or an entry of the notification history of a system
+type Diagnostic struct {
+	Type         DiagnosticType
+	DeviceID     string
+	SerialNumber string
+	Code         string // e.g. "F.22" or "M.20"
+	Description  string
+	Timestamp    time.Time
+}
+
+// deviceTroubleCodes is the response of DIAGNOSTICS_URL for one device
+type deviceTroubleCodes struct {
+	DeviceID     string `json:"deviceId"`
+	SerialNumber string `json:"serialNumber"`
+	Codes        []struct {
+		CodeType            string    `json:"codeType"` // "F" (error), "S" (status) or "M" (maintenance)
+		CodeNumber          int       `json:"codeNumber"`
+		Description         string    `json:"description"`
+		OccurrenceTimestamp time.Time `json:"occurrenceTimestamp"`
+	} `json:"codes"`
+}
+
+// notification is the response of NOTIFICATIONS_URL for one notification
+type notification struct {
+	DeviceID  string    `json:"deviceId"`
+	Code      string    `json:"code"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Ids of common values of the live report
+const (
+	LIVEREPORT_TANK_TEMPERATURE      = "DomesticHotWaterTankTemperature"
+	LIVEREPORT_FLOW_TEMPERATURE      = "FlowTemperature"
+	LIVEREPORT_RETURN_TEMPERATURE    = "ReturnTemperature"
+	LIVEREPORT_COMPRESSOR_MODULATION = "CompressorModulation"
+)
+
+// LiveReport contains the current sensor values of the devices of a system
+type LiveReport struct {
+	Devices []LiveReportDevice `json:"devices"`
+}
+
+type LiveReportDevice struct {
+	ID      string            `json:"id"`
+	Reports []LiveReportValue `json:"reports"`
+}
+
+type LiveReportValue struct {
+	ID                  string  `json:"id"`
+	Value               float64 `json:"value"`
+	Unit                string  `json:"unit"`
+	MeasurementCategory string  `json:"measurementCategory"`
+}
+
+// SystemDiagnostics contains the active codes and the notification history of a system
+type SystemDiagnostics struct {
+	Diagnostics   []Diagnostic
+	Notifications []Diagnostic
+}
+
 type DevicePower struct {
 	CurrentPower float64
 	ProductName  string